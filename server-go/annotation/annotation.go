@@ -0,0 +1,138 @@
+package annotation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirName 批注存储目录（相对于 wiki-docs/）
+const DirName = ".annotations"
+
+// Annotation 单条批注（与 shared/types.ts 中的 Annotation 对应）
+type Annotation struct {
+	ID           string `json:"id"`
+	SelectedText string `json:"selectedText"`
+	Comment      string `json:"comment"`
+	StartOffset  int    `json:"startOffset"`
+	EndOffset    int    `json:"endOffset"`
+	AnchorLine   *int   `json:"anchorLine"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"createdAt"`
+	UpdatedAt    string `json:"updatedAt"`
+	// Suggestion 建议的替换文本（可直接采纳为修改）；nil 表示没有建议，空字符串表示建议删除原文
+	Suggestion *string `json:"suggestion,omitempty"`
+	AppliedBy  string  `json:"appliedBy,omitempty"`
+	AppliedAt  string  `json:"appliedAt,omitempty"`
+}
+
+// File 单个文档的批注集合（持久化 JSON 格式）
+type File struct {
+	Version     int           `json:"version"`
+	FilePath    string        `json:"filePath"`
+	Annotations []*Annotation `json:"annotations"`
+}
+
+// FileName 将文档路径转为批注文件名：a/b/c.md → a_b_c.json
+func FileName(docPath string) string {
+	name := strings.ReplaceAll(docPath, "/", "_")
+	name = strings.ReplaceAll(name, "\\", "_")
+	name = strings.TrimSuffix(name, ".md")
+	return name + ".json"
+}
+
+// Path 返回文档对应批注文件的完整路径
+func Path(rootDir, docPath string) string {
+	return filepath.Join(rootDir, DirName, FileName(docPath))
+}
+
+// Load 读取单个文档的批注文件
+func Load(rootDir, docPath string) (*File, error) {
+	content, err := os.ReadFile(Path(rootDir, docPath))
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Save 格式化写入批注文件（便于人类阅读和 agent 解析）
+func Save(rootDir string, f *File) error {
+	if err := os.MkdirAll(filepath.Join(rootDir, DirName), 0755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(Path(rootDir, f.FilePath), content, 0644)
+}
+
+// Find 按 ID 查找批注
+func (f *File) Find(id string) *Annotation {
+	for _, a := range f.Annotations {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// KeepApplied 保留 stored 中已采纳批注的状态和采纳记录
+// 前端整体保存批注列表，过期的页面不能把已采纳的批注改回待处理，否则建议会被重复采纳
+func (f *File) KeepApplied(stored *File) {
+	for _, old := range stored.Annotations {
+		if old.AppliedAt == "" {
+			continue
+		}
+		if a := f.Find(old.ID); a != nil {
+			a.Status, a.AppliedBy, a.AppliedAt = old.Status, old.AppliedBy, old.AppliedAt
+		}
+	}
+}
+
+// LoadAll 读取 rootDir/.annotations 下的全部批注文件，按文档路径排序
+// 目录不存在时返回空列表
+func LoadAll(rootDir string) ([]*File, error) {
+	dir := filepath.Join(rootDir, DirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []*File
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var f File
+		if err := json.Unmarshal(content, &f); err != nil || !within(rootDir, f.FilePath) {
+			// 损坏的批注文件（包括 filePath 指向 rootDir 之外）不影响其他文档
+			continue
+		}
+		files = append(files, &f)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].FilePath < files[j].FilePath
+	})
+	return files, nil
+}
+
+// within 判断文档路径是否位于 rootDir 内
+func within(rootDir, docPath string) bool {
+	rel, err := filepath.Rel(rootDir, filepath.Join(rootDir, filepath.FromSlash(docPath)))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
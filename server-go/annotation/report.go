@@ -0,0 +1,344 @@
+package annotation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contextLines 报告中每条批注前后附带的上下文行数
+const contextLines = 1
+
+// statusOrder 报告中状态分组的固定顺序，未知状态排在最后
+var statusOrder = []string{"open", "resolved", "rejected"}
+
+var statusLabels = map[string]string{
+	"open":     "待处理",
+	"resolved": "已解决",
+	"rejected": "已驳回",
+}
+
+// Report 批注审校报告
+type Report struct {
+	GeneratedAt string            `json:"generatedAt"`
+	Total       int               `json:"total"`
+	Counts      map[string]int    `json:"counts"`
+	Documents   []*DocumentReport `json:"documents"`
+}
+
+// DocumentReport 单个文档的批注，按状态分组
+type DocumentReport struct {
+	FilePath string         `json:"filePath"`
+	Missing  bool           `json:"missing,omitempty"`
+	Groups   []*StatusGroup `json:"groups"`
+}
+
+// StatusGroup 同一状态下的批注
+type StatusGroup struct {
+	Status string  `json:"status"`
+	Items  []*Item `json:"items"`
+}
+
+// Item 报告中的单条批注
+type Item struct {
+	ID           string  `json:"id"`
+	SelectedText string  `json:"selectedText"`
+	Comment      string  `json:"comment"`
+	Suggestion   *string `json:"suggestion,omitempty"`
+	Line         int     `json:"line"`
+	Context      string  `json:"context"`
+	Stale        bool    `json:"stale,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
+	UpdatedAt    string  `json:"updatedAt"`
+}
+
+// BuildReport 汇总 rootDir 下所有文档的批注
+// statuses 为空时包含全部状态；行号和上下文取自文档当前内容
+func BuildReport(rootDir string, statuses []string) (*Report, error) {
+	files, err := LoadAll(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Counts:      make(map[string]int),
+		Documents:   []*DocumentReport{},
+	}
+
+	for _, f := range files {
+		doc := &DocumentReport{FilePath: f.FilePath}
+
+		var lines []string
+		content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(f.FilePath)))
+		if err != nil {
+			doc.Missing = true
+		} else {
+			lines = strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+		}
+
+		groups := make(map[string]*StatusGroup)
+		for _, a := range f.Annotations {
+			if len(statuses) > 0 && !slices.Contains(statuses, a.Status) {
+				continue
+			}
+			line, found := locate(lines, a)
+			item := &Item{
+				ID:           a.ID,
+				SelectedText: a.SelectedText,
+				Comment:      a.Comment,
+				Suggestion:   a.Suggestion,
+				Line:         line,
+				Context:      surrounding(lines, line),
+				Stale:        !doc.Missing && !found,
+				CreatedAt:    a.CreatedAt,
+				UpdatedAt:    a.UpdatedAt,
+			}
+			g, ok := groups[a.Status]
+			if !ok {
+				g = &StatusGroup{Status: a.Status}
+				groups[a.Status] = g
+			}
+			g.Items = append(g.Items, item)
+			report.Counts[a.Status]++
+			report.Total++
+		}
+		if len(groups) == 0 {
+			continue
+		}
+
+		for _, s := range sortedStatuses(groups) {
+			g := groups[s]
+			slices.SortStableFunc(g.Items, func(a, b *Item) int { return a.Line - b.Line })
+			doc.Groups = append(doc.Groups, g)
+		}
+		report.Documents = append(report.Documents, doc)
+	}
+
+	return report, nil
+}
+
+// Write 按格式输出报告，format 为 md / csv / json
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "", "md", "markdown":
+		return r.WriteMarkdown(w)
+	case "csv":
+		return r.WriteCSV(w)
+	case "json":
+		return r.WriteJSON(w)
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// ContentType 返回导出格式对应的 MIME 类型
+func ContentType(format string) string {
+	switch format {
+	case "csv":
+		return "text/csv; charset=utf-8"
+	case "json":
+		return "application/json; charset=utf-8"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// WriteJSON 输出 JSON 格式报告
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV 输出 CSV 格式报告（每条批注一行）
+func (r *Report) WriteCSV(w io.Writer) error {
+	// UTF-8 BOM，保证 Excel 直接打开时中文不乱码
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"filePath", "status", "line", "selectedText", "comment", "suggestion", "context", "stale", "id", "createdAt", "updatedAt"})
+	for _, doc := range r.Documents {
+		for _, g := range doc.Groups {
+			for _, it := range g.Items {
+				cw.Write([]string{
+					doc.FilePath,
+					g.Status,
+					strconv.Itoa(it.Line),
+					it.SelectedText,
+					it.Comment,
+					deref(it.Suggestion),
+					it.Context,
+					strconv.FormatBool(it.Stale),
+					it.ID,
+					it.CreatedAt,
+					it.UpdatedAt,
+				})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarkdown 输出 Markdown 格式报告
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("# 审校批注报告\n\n")
+	fmt.Fprintf(&b, "生成时间：%s\n\n", r.GeneratedAt)
+
+	var summary []string
+	for _, s := range sortedStatuses(r.Counts) {
+		summary = append(summary, fmt.Sprintf("%s %d", statusLabel(s), r.Counts[s]))
+	}
+	fmt.Fprintf(&b, "共 %d 条批注", r.Total)
+	if len(summary) > 0 {
+		fmt.Fprintf(&b, "（%s）", strings.Join(summary, " / "))
+	}
+	b.WriteString("\n")
+
+	for _, doc := range r.Documents {
+		fmt.Fprintf(&b, "\n## %s\n", doc.FilePath)
+		if doc.Missing {
+			b.WriteString("\n> 文档已不存在，以下批注无法定位。\n")
+		}
+		for _, g := range doc.Groups {
+			fmt.Fprintf(&b, "\n### %s · %d 条\n\n", statusLabel(g.Status), len(g.Items))
+			for _, it := range g.Items {
+				lineLabel := "L?"
+				if it.Line > 0 {
+					lineLabel = fmt.Sprintf("L%d", it.Line)
+				}
+				fmt.Fprintf(&b, "- **%s** 「%s」", lineLabel, oneLine(it.SelectedText))
+				if it.Stale {
+					b.WriteString(" *（原文已变更）*")
+				}
+				b.WriteString("\n")
+				fmt.Fprintf(&b, "  - 批注：%s\n", oneLine(it.Comment))
+				if it.Suggestion != nil {
+					if *it.Suggestion == "" {
+						b.WriteString("  - 建议删除原文\n")
+					} else {
+						fmt.Fprintf(&b, "  - 建议修改为：「%s」\n", oneLine(*it.Suggestion))
+					}
+				}
+				if it.Context != "" {
+					fence := codeFence(it.Context)
+					b.WriteString("  - 上下文：\n\n")
+					fmt.Fprintf(&b, "    %s\n", fence)
+					for _, l := range strings.Split(it.Context, "\n") {
+						fmt.Fprintf(&b, "    %s\n", l)
+					}
+					fmt.Fprintf(&b, "    %s\n\n", fence)
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// locate 在文档当前内容中定位批注所在行（1-based）
+// 优先选取距离 anchorLine 最近的原文出现位置；找不到原文时退回 anchorLine
+func locate(lines []string, a *Annotation) (int, bool) {
+	anchor := 0
+	if a.AnchorLine != nil {
+		anchor = *a.AnchorLine
+	}
+
+	// 跨块选区只用首行匹配
+	needle := strings.TrimSpace(strings.SplitN(a.SelectedText, "\n", 2)[0])
+	if needle == "" || len(lines) == 0 {
+		return anchor, false
+	}
+
+	best := 0
+	for i, l := range lines {
+		if !strings.Contains(l, needle) {
+			continue
+		}
+		if best == 0 || abs(i+1-anchor) < abs(best-anchor) {
+			best = i + 1
+		}
+	}
+	if best == 0 {
+		return anchor, false
+	}
+	return best, true
+}
+
+// surrounding 返回 line 前后 contextLines 行的内容
+func surrounding(lines []string, line int) string {
+	if line <= 0 || line > len(lines) {
+		return ""
+	}
+	start := max(line-1-contextLines, 0)
+	end := min(line+contextLines, len(lines))
+	return strings.TrimRight(strings.Join(lines[start:end], "\n"), "\n")
+}
+
+func sortedStatuses[V any](m map[string]V) []string {
+	var result []string
+	for _, s := range statusOrder {
+		if _, ok := m[s]; ok {
+			result = append(result, s)
+		}
+	}
+	var rest []string
+	for s := range m {
+		if !slices.Contains(statusOrder, s) {
+			rest = append(rest, s)
+		}
+	}
+	slices.Sort(rest)
+	return append(result, rest...)
+}
+
+func statusLabel(status string) string {
+	if label, ok := statusLabels[status]; ok {
+		return fmt.Sprintf("%s（%s）", label, status)
+	}
+	return status
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// codeFence 生成比内容中最长反引号序列更长的代码围栏
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,130 @@
+package annotation
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeAnnotations(t *testing.T, rootDir, docPath, content string) {
+	t.Helper()
+	dir := filepath.Join(rootDir, DirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName(docPath)), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildReport_GroupsAndLocates(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "角色系统"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "角色系统", "冲击.md"), []byte("# 冲击\n\n冲击会打断施法。\n\n冲击力度由武器决定。\n"), 0644)
+
+	writeAnnotations(t, tmpDir, "角色系统/冲击.md", `{
+  "version": 1,
+  "filePath": "角色系统/冲击.md",
+  "annotations": [
+    {"id": "a", "selectedText": "武器", "comment": "补充武器表", "anchorLine": 5, "status": "open"},
+    {"id": "b", "selectedText": "打断施法", "comment": "确认", "anchorLine": null, "status": "resolved"},
+    {"id": "c", "selectedText": "已删除的文字", "comment": "过期", "anchorLine": 3, "status": "open"}
+  ]
+}`)
+	writeAnnotations(t, tmpDir, "已删除.md", `{"version":1,"filePath":"已删除.md","annotations":[{"id":"d","selectedText":"x","comment":"y","anchorLine":1,"status":"open"}]}`)
+
+	report, err := BuildReport(tmpDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 4 || report.Counts["open"] != 3 || report.Counts["resolved"] != 1 {
+		t.Fatalf("统计错误: total=%d counts=%v", report.Total, report.Counts)
+	}
+	if len(report.Documents) != 2 {
+		t.Fatalf("期望 2 个文档，实际 %d", len(report.Documents))
+	}
+
+	doc := report.Documents[1]
+	if doc.FilePath != "角色系统/冲击.md" {
+		t.Fatalf("文档排序错误: %s", doc.FilePath)
+	}
+	if doc.Groups[0].Status != "open" || doc.Groups[1].Status != "resolved" {
+		t.Fatalf("状态分组顺序错误: %s, %s", doc.Groups[0].Status, doc.Groups[1].Status)
+	}
+
+	open := doc.Groups[0].Items
+	if open[0].ID != "c" || !open[0].Stale || open[0].Line != 3 {
+		t.Errorf("失效批注应退回 anchorLine 并标记 stale: %+v", open[0])
+	}
+	if open[1].ID != "a" || open[1].Line != 5 || open[1].Context != "\n冲击力度由武器决定。" {
+		t.Errorf("定位错误: %+v", open[1])
+	}
+	if resolved := doc.Groups[1].Items[0]; resolved.Line != 3 || resolved.Stale {
+		t.Errorf("无 anchorLine 时应按原文定位: %+v", resolved)
+	}
+
+	if !report.Documents[0].Missing {
+		t.Error("文档不存在时应标记 missing")
+	}
+
+	var md bytes.Buffer
+	if err := report.Write(&md, "md"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "## 角色系统/冲击.md") || !strings.Contains(md.String(), "**L5** 「武器」") {
+		t.Errorf("Markdown 输出缺少内容:\n%s", md.String())
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\uFEFF"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Errorf("CSV 期望 1 行表头 + 4 行数据，实际 %d 行", len(rows))
+	}
+}
+
+func TestBuildReport_StatusFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.md"), []byte("内容\n"), 0644)
+	writeAnnotations(t, tmpDir, "a.md", `{"version":1,"filePath":"a.md","annotations":[
+		{"id":"1","selectedText":"内容","comment":"c","status":"open"},
+		{"id":"2","selectedText":"内容","comment":"c","status":"rejected"}]}`)
+
+	report, err := BuildReport(tmpDir, []string{"open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 1 || len(report.Documents[0].Groups) != 1 {
+		t.Errorf("状态过滤失败: %+v", report.Counts)
+	}
+
+	if err := report.Write(&bytes.Buffer{}, "pdf"); err == nil {
+		t.Error("不支持的格式应返回错误")
+	}
+}
+
+func TestBuildReport_RejectsEscapingPaths(t *testing.T) {
+	parent := t.TempDir()
+	tmpDir := filepath.Join(parent, "wiki")
+	os.MkdirAll(tmpDir, 0755)
+	os.WriteFile(filepath.Join(parent, "secret.md"), []byte("机密\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "a.md"), []byte("内容\n"), 0644)
+	writeAnnotations(t, tmpDir, "a.md", `{"version":1,"filePath":"a.md","annotations":[{"id":"1","selectedText":"内容","comment":"c","status":"open"}]}`)
+	writeAnnotations(t, tmpDir, "secret.md", `{"version":1,"filePath":"../secret.md","annotations":[{"id":"2","selectedText":"机密","comment":"c","anchorLine":1,"status":"open"}]}`)
+
+	report, err := BuildReport(tmpDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 1 || len(report.Documents) != 1 || report.Documents[0].FilePath != "a.md" {
+		t.Errorf("filePath 指向文档目录之外的批注应被忽略: %+v", report.Documents)
+	}
+}
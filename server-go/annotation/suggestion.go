@@ -0,0 +1,63 @@
+package annotation
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoSuggestion 批注未携带建议修改
+	ErrNoSuggestion = errors.New("批注没有建议修改")
+	// ErrNotOpen 只有待处理的批注可以采纳
+	ErrNotOpen = errors.New("批注已处理，不能重复采纳")
+	// ErrTextNotFound 文档中已找不到批注选中的原文
+	ErrTextNotFound = errors.New("文档中找不到批注选中的原文，可能已被修改")
+)
+
+// ApplySuggestion 用建议文本替换文档中选中的原文，返回修改后的内容
+// 原文出现多次时，选取距离 anchorLine 最近的一处
+func (a *Annotation) ApplySuggestion(content string) (string, error) {
+	if a.Suggestion == nil {
+		return "", ErrNoSuggestion
+	}
+	if a.Status != "open" {
+		return "", ErrNotOpen
+	}
+	if a.SelectedText == "" {
+		return "", ErrTextNotFound
+	}
+
+	anchor := 0
+	if a.AnchorLine != nil {
+		anchor = *a.AnchorLine
+	}
+
+	best, bestLine := -1, 0
+	for offset := 0; ; {
+		i := strings.Index(content[offset:], a.SelectedText)
+		if i < 0 {
+			break
+		}
+		pos := offset + i
+		line := strings.Count(content[:pos], "\n") + 1
+		if best < 0 || abs(line-anchor) < abs(bestLine-anchor) {
+			best, bestLine = pos, line
+		}
+		offset = pos + len(a.SelectedText)
+	}
+	if best < 0 {
+		return "", ErrTextNotFound
+	}
+
+	return content[:best] + *a.Suggestion + content[best+len(a.SelectedText):], nil
+}
+
+// MarkApplied 将批注标记为已采纳（resolved），记录采纳人和时间
+func (a *Annotation) MarkApplied(by string, at time.Time) {
+	stamp := at.UTC().Format(time.RFC3339)
+	a.Status = "resolved"
+	a.AppliedBy = by
+	a.AppliedAt = stamp
+	a.UpdatedAt = stamp
+}
@@ -0,0 +1,71 @@
+package annotation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApplySuggestion(t *testing.T) {
+	content := "# 建筑\n\n玩家可以删除建筑。\n\n删除建筑会返还资源。\n"
+	line := 5
+	fix, remove := "拆除建筑", ""
+
+	tests := []struct {
+		name    string
+		a       Annotation
+		want    string
+		wantErr error
+	}{
+		{
+			name: "选取距离 anchorLine 最近的一处",
+			a:    Annotation{SelectedText: "删除建筑", Suggestion: &fix, AnchorLine: &line, Status: "open"},
+			want: "# 建筑\n\n玩家可以删除建筑。\n\n拆除建筑会返还资源。\n",
+		},
+		{
+			name: "无 anchorLine 时替换第一处",
+			a:    Annotation{SelectedText: "删除建筑", Suggestion: &fix, Status: "open"},
+			want: "# 建筑\n\n玩家可以拆除建筑。\n\n删除建筑会返还资源。\n",
+		},
+		{
+			name: "空建议删除原文",
+			a:    Annotation{SelectedText: "删除建筑会返还资源。", Suggestion: &remove, AnchorLine: &line, Status: "open"},
+			want: "# 建筑\n\n玩家可以删除建筑。\n\n\n",
+		},
+		{
+			name:    "原文已被修改",
+			a:       Annotation{SelectedText: "摧毁建筑", Suggestion: &fix, Status: "open"},
+			wantErr: ErrTextNotFound,
+		},
+		{
+			name:    "没有建议",
+			a:       Annotation{SelectedText: "删除建筑", Status: "open"},
+			wantErr: ErrNoSuggestion,
+		},
+		{
+			name:    "已处理的批注",
+			a:       Annotation{SelectedText: "删除建筑", Suggestion: &fix, Status: "resolved"},
+			wantErr: ErrNotOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.a.ApplySuggestion(content)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkApplied(t *testing.T) {
+	a := &Annotation{Status: "open"}
+	a.MarkApplied("小林", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	if a.Status != "resolved" || a.AppliedBy != "小林" || a.AppliedAt != "2026-01-02T03:04:05Z" || a.UpdatedAt != a.AppliedAt {
+		t.Errorf("MarkApplied 结果错误: %+v", a)
+	}
+}
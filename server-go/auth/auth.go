@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CookieName 通过 ?token= 登录后保存令牌的 Cookie
+const CookieName = "xlxz-wiki-token"
+
+// Role 访问角色，按权限从低到高排列
+type Role int
+
+const (
+	None     Role = iota // 未登录且不允许匿名访问
+	Viewer               // 只读浏览
+	Reviewer             // 浏览 + 审校批注
+	Editor               // 浏览 + 审校 + 编辑文档
+)
+
+var roleNames = [...]string{"none", "viewer", "reviewer", "editor"}
+
+func (r Role) String() string {
+	if r < None || r > Editor {
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+	return roleNames[r]
+}
+
+// MarshalText 角色在 JSON 中以名称表示
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// ParseRole 解析角色名称
+func ParseRole(s string) (Role, error) {
+	for i, name := range roleNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return Role(i), nil
+		}
+	}
+	return None, fmt.Errorf("未知角色 %q（可选 viewer / reviewer / editor / none）", s)
+}
+
+// User 已识别的访问者，匿名访问者 Name 为空
+type User struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+type account struct {
+	name string
+	hash [sha256.Size]byte // 密钥的 SHA-256
+	role Role
+}
+
+// Authenticator 根据配置的账号识别访问者并按角色放行
+type Authenticator struct {
+	accounts  []account
+	anonymous Role
+}
+
+// New 解析 "名称:密钥:角色" 形式的账号，密钥可写成 sha256:<十六进制> 避免明文
+// anonymous 为未登录访问者的角色；留空时未配置账号为 viewer，否则为 none，允许匿名编辑需显式设为 editor
+func New(entries []string, anonymous string) (*Authenticator, error) {
+	a := &Authenticator{}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		acc, err := parseAccount(entry)
+		if err != nil {
+			return nil, err
+		}
+		if seen[acc.name] {
+			return nil, fmt.Errorf("重复的账号 %s", acc.name)
+		}
+		seen[acc.name] = true
+		a.accounts = append(a.accounts, acc)
+	}
+
+	switch {
+	case anonymous != "":
+		role, err := ParseRole(anonymous)
+		if err != nil {
+			return nil, err
+		}
+		a.anonymous = role
+	case len(a.accounts) == 0:
+		a.anonymous = Viewer
+	default:
+		a.anonymous = None
+	}
+	return a, nil
+}
+
+func parseAccount(entry string) (account, error) {
+	first := strings.Index(entry, ":")
+	last := strings.LastIndex(entry, ":")
+	if first <= 0 || last == first {
+		return account{}, fmt.Errorf("账号格式应为 名称:密钥:角色: %q", Redact(entry))
+	}
+	name, secret := entry[:first], entry[first+1:last]
+	role, err := ParseRole(entry[last+1:])
+	if err != nil {
+		return account{}, fmt.Errorf("账号 %s: %w", name, err)
+	}
+	if role == None {
+		return account{}, fmt.Errorf("账号 %s: 角色不能为 none", name)
+	}
+	if secret == "" {
+		return account{}, fmt.Errorf("账号 %s: 密钥不能为空", name)
+	}
+
+	acc := account{name: name, role: role}
+	if hexHash, ok := strings.CutPrefix(secret, "sha256:"); ok {
+		b, err := hex.DecodeString(hexHash)
+		if err != nil || len(b) != sha256.Size {
+			return account{}, fmt.Errorf("账号 %s: sha256 密钥应为 64 位十六进制", name)
+		}
+		copy(acc.hash[:], b)
+	} else {
+		acc.hash = sha256.Sum256([]byte(secret))
+	}
+	return acc, nil
+}
+
+// Redact 隐藏账号配置中的密钥，用于输出配置和错误信息
+func Redact(entry string) string {
+	first := strings.Index(entry, ":")
+	last := strings.LastIndex(entry, ":")
+	if first < 0 || last == first {
+		return "***"
+	}
+	return entry[:first] + ":***" + entry[last:]
+}
+
+// Enabled 是否配置了账号
+func (a *Authenticator) Enabled() bool {
+	return len(a.accounts) > 0
+}
+
+// Anonymous 未登录访问者的角色
+func (a *Authenticator) Anonymous() Role {
+	return a.anonymous
+}
+
+// Identify 从请求中识别访问者：Basic 认证、Bearer 令牌、token 查询参数或 Cookie
+// 提供了凭据但不匹配时 ok 为 false；没有凭据时返回匿名访问者
+func (a *Authenticator) Identify(r *http.Request) (user User, ok bool) {
+	if name, secret, found := r.BasicAuth(); found {
+		return a.match(name, secret)
+	}
+	if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return a.match("", bearer)
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return a.match("", token)
+	}
+	if cookie, err := r.Cookie(CookieName); err == nil {
+		return a.match("", cookie.Value)
+	}
+	return User{Role: a.anonymous}, true
+}
+
+// match 按密钥查找账号，name 非空时还要求名称一致
+func (a *Authenticator) match(name, secret string) (User, bool) {
+	hash := sha256.Sum256([]byte(secret))
+	for _, acc := range a.accounts {
+		if subtle.ConstantTimeCompare(hash[:], acc.hash[:]) == 1 && (name == "" || name == acc.name) {
+			return User{Name: acc.name, Role: acc.role}, true
+		}
+	}
+	return User{}, false
+}
+
+// Require 返回只允许 role 及以上角色访问的处理函数
+// 未登录返回 401（浏览器弹出登录框），权限不足返回 403
+// 通过 ?token= 登录成功时写入 Cookie，之后的请求（包括 WebSocket）无需再带令牌
+func (a *Authenticator) Require(role Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := a.Identify(r)
+		if !ok || user.Role < role {
+			if !ok || (user.Name == "" && a.Enabled()) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="XLXZ Wiki", charset="UTF-8"`)
+				http.Error(w, "需要登录", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "权限不足，需要 "+role.String()+" 角色", http.StatusForbidden)
+			return
+		}
+
+		if token := r.URL.Query().Get("token"); token != "" && user.Name != "" {
+			http.SetCookie(w, &http.Cookie{
+				Name:     CookieName,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil || r.URL.Scheme == "https", // 反向代理的 X-Forwarded-Proto 仅在 trustProxy 时写入 Scheme
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	}
+}
+
+type userKey struct{}
+
+// FromRequest 返回 Require 识别出的访问者
+func FromRequest(r *http.Request) User {
+	user, _ := r.Context().Value(userKey{}).(User)
+	return user
+}
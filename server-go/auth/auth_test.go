@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAuth(t *testing.T, anonymous string) *Authenticator {
+	t.Helper()
+	hash := sha256.Sum256([]byte("design-secret"))
+	a, err := New([]string{
+		"外包:view-token:viewer",
+		"qa:review-token:reviewer",
+		"策划:sha256:" + hex.EncodeToString(hash[:]) + ":editor",
+	}, anonymous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func serve(a *Authenticator, role Role, req *http.Request) (*httptest.ResponseRecorder, User) {
+	var got User
+	rec := httptest.NewRecorder()
+	a.Require(role, func(w http.ResponseWriter, r *http.Request) {
+		got = FromRequest(r)
+	})(rec, req)
+	return rec, got
+}
+
+func TestRequireRoles(t *testing.T) {
+	a := newAuth(t, "")
+
+	tests := []struct {
+		name     string
+		setup    func(r *http.Request)
+		role     Role
+		wantCode int
+		wantUser string
+	}{
+		{"anonymous needs login", func(r *http.Request) {}, Viewer, 401, ""},
+		{"viewer via bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer view-token") }, Viewer, 200, "外包"},
+		{"viewer cannot review", func(r *http.Request) { r.Header.Set("Authorization", "Bearer view-token") }, Reviewer, 403, ""},
+		{"reviewer via cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: CookieName, Value: "review-token"}) }, Reviewer, 200, "qa"},
+		{"reviewer cannot edit", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: CookieName, Value: "review-token"}) }, Editor, 403, ""},
+		{"editor via basic with hashed secret", func(r *http.Request) { r.SetBasicAuth("策划", "design-secret") }, Editor, 200, "策划"},
+		{"basic with wrong name", func(r *http.Request) { r.SetBasicAuth("qa", "design-secret") }, Viewer, 401, ""},
+		{"wrong token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, None, 401, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/file", nil)
+			tt.setup(req)
+			rec, user := serve(a, tt.role, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if user.Name != tt.wantUser {
+				t.Errorf("user = %q, want %q", user.Name, tt.wantUser)
+			}
+			if tt.wantCode == 401 && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestQueryTokenSetsCookie(t *testing.T) {
+	a := newAuth(t, "")
+	rec, user := serve(a, Viewer, httptest.NewRequest("GET", "/?token=view-token", nil))
+	if rec.Code != 200 || user.Name != "外包" {
+		t.Fatalf("code = %d, user = %+v", rec.Code, user)
+	}
+	cookie := rec.Header().Get("Set-Cookie")
+	if !strings.HasPrefix(cookie, CookieName+"=view-token") || !strings.Contains(cookie, "HttpOnly") || strings.Contains(cookie, "Secure") {
+		t.Errorf("Set-Cookie = %q", cookie)
+	}
+
+	// 未经信任的 X-Forwarded-Proto 不影响 Secure；https 请求（含 trustProxy 还原的协议）才带 Secure
+	r := httptest.NewRequest("GET", "/?token=view-token", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if rec, _ := serve(a, Viewer, r); strings.Contains(rec.Header().Get("Set-Cookie"), "Secure") {
+		t.Errorf("不应信任 X-Forwarded-Proto: %q", rec.Header().Get("Set-Cookie"))
+	}
+	if rec, _ := serve(a, Viewer, httptest.NewRequest("GET", "https://wiki.example.com/?token=view-token", nil)); !strings.Contains(rec.Header().Get("Set-Cookie"), "Secure") {
+		t.Errorf("https 请求的 Cookie 应带 Secure: %q", rec.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestAnonymousRole(t *testing.T) {
+	open, _ := New(nil, "")
+	if rec, _ := serve(open, Editor, httptest.NewRequest("POST", "/api/file", nil)); rec.Code != 403 {
+		t.Errorf("without accounts anonymous should be viewer: code = %d, want 403", rec.Code)
+	}
+	editable, _ := New(nil, "editor")
+	if _, user := serve(editable, Editor, httptest.NewRequest("POST", "/api/file", nil)); user.Role != Editor {
+		t.Errorf("explicit anonymous editor, got %s", user.Role)
+	}
+
+	a := newAuth(t, "viewer")
+	rec, user := serve(a, Viewer, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != 200 || user.Name != "" || user.Role != Viewer {
+		t.Errorf("code = %d, user = %+v", rec.Code, user)
+	}
+	if rec, _ := serve(a, Reviewer, httptest.NewRequest("POST", "/api/annotations", nil)); rec.Code != 401 {
+		t.Errorf("anonymous viewer reviewing: code = %d, want 401", rec.Code)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		entries   []string
+		anonymous string
+		want      string
+	}{
+		{[]string{"alice"}, "", "名称:密钥:角色"},
+		{[]string{"alice:pw:admin"}, "", "未知角色"},
+		{[]string{"alice:pw:none"}, "", "不能为 none"},
+		{[]string{"alice::editor"}, "", "密钥不能为空"},
+		{[]string{"alice:sha256:abc:editor"}, "", "64 位"},
+		{[]string{"alice:a:viewer", "alice:b:editor"}, "", "重复"},
+		{nil, "owner", "未知角色"},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.entries, tt.anonymous)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("New(%q, %q) err = %v, want containing %q", tt.entries, tt.anonymous, err, tt.want)
+		}
+		if err != nil && strings.Contains(err.Error(), ":pw:") {
+			t.Errorf("error leaks secret: %v", err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"xlxz-wiki/annotation"
+	"xlxz-wiki/config"
+	"xlxz-wiki/lint"
+	"xlxz-wiki/server"
+)
+
+// commands 子命令表：xlxz-wiki <command> [flags]
+// 返回值作为进程退出码
+var commands = map[string]func(args []string) int{
+	"annotations": runAnnotations,
+	"config":      runConfig,
+	"export":      runExport,
+	"lint":        runLint,
+	"bracketize":  runBracketize,
+}
+
+// runAnnotations 导出审校批注报告
+// 用法：xlxz-wiki annotations [-config xlxz-wiki.yaml] [-docs wiki-docs] [-workspace 名称] -format md -status open -o report.md
+func runAnnotations(args []string) int {
+	fs := flag.NewFlagSet("annotations", flag.ContinueOnError)
+	loadConfig := configFlags(fs)
+	workspace := fs.String("workspace", "", "导出的工作区，默认为第一个")
+	format := fs.String("format", "md", "导出格式：md / csv / json")
+	status := fs.String("status", "", "只导出指定状态，多个用逗号分隔（如 open,rejected）")
+	output := fs.String("o", "", "输出文件路径，默认输出到标准输出")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var statuses []string
+	if *status != "" {
+		statuses = strings.Split(*status, ",")
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	docsDir, err := workspaceDocs(c, *workspace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	report, err := annotation.BuildReport(docsDir, statuses)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取批注失败: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建输出文件失败: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := report.Write(w, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// workspaceDocs 返回工作区的文档目录，name 为空时为第一个工作区
+func workspaceDocs(c *config.Config, name string) (string, error) {
+	list, err := c.WorkspaceList()
+	if err != nil {
+		return "", err
+	}
+	for _, w := range list {
+		if name == "" || w.Name == name {
+			return w.Docs, nil
+		}
+	}
+	return "", fmt.Errorf("未知工作区: %s", name)
+}
+
+// runConfig 查看生效的配置
+// 用法：xlxz-wiki config print [-config xlxz-wiki.yaml] [-docs wiki-docs] ...
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "用法: xlxz-wiki config print [参数]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	loadConfig := configFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	if err := c.WriteYAML(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "输出失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runExport 导出静态站点，可打包分享或放到任意静态文件服务器上浏览；
+// -single 导出为单个 HTML 文件，双击即可打开
+// 用法：xlxz-wiki export [-config xlxz-wiki.yaml] [-docs wiki-docs] [-workspace 名称] [-single] -o wiki-site
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	loadConfig := configFlags(fs)
+	output := fs.String("o", "", "输出目录，默认为 wiki-site；-single 时为输出文件，默认为 wiki.html")
+	workspace := fs.String("workspace", "", "导出的工作区，默认为第一个")
+	single := fs.Bool("single", false, "导出为单个 HTML 文件")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *output == "" {
+		*output = "wiki-site"
+		if *single {
+			*output = "wiki.html"
+		}
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	srv, err := server.New(server.Config{Config: *c, Assets: assets(), Version: Version})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载文档失败: %v\n", err)
+		return 1
+	}
+	if *single {
+		err = exportBundle(srv, *output, *workspace)
+	} else {
+		err = srv.ExportStatic(*output, *workspace)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("已导出到 %s\n", *output)
+	return 0
+}
+
+// runLint 检查文档，发现问题时退出码为 1，便于在提交前或 CI 中使用；
+// -fix 按替换建议修改文档，只输出修改后仍存在的问题
+// 用法：xlxz-wiki lint [-docs wiki-docs] [-workspace 名称] [-format text|json] [-fix]
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	loadConfig := configFlags(fs)
+	workspace := fs.String("workspace", "", "检查的工作区，默认为第一个")
+	format := fs.String("format", "text", "输出格式：text / json")
+	fix := fs.Bool("fix", false, "按替换建议修改文档")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	if *fix && c.ReadOnly {
+		fmt.Fprintln(os.Stderr, "只读模式下不能修改文档")
+		return 1
+	}
+	srv, err := server.New(server.Config{Config: *c, Version: Version, SkipCacheWrite: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载文档失败: %v\n", err)
+		return 1
+	}
+	issues, err := srv.Lint(*workspace)
+	if err == nil && *fix {
+		var n int
+		n, err = srv.LintFix(*workspace, issues)
+		if err == nil && n > 0 {
+			fmt.Fprintf(os.Stderr, "已修复 %d 处\n", n)
+			issues, err = srv.Lint(*workspace)
+		}
+	}
+	if err == nil {
+		err = lint.Write(os.Stdout, issues, *format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "检查失败: %v\n", err)
+		return 1
+	}
+	if len(issues) > 0 {
+		if *format == "text" {
+			fmt.Fprintf(os.Stderr, "发现 %d 个问题\n", len(issues))
+		}
+		return 1
+	}
+	return 0
+}
+
+// runBracketize 为未加括号的词条和别名加上 【】，默认只输出 unified diff 供审阅，-write 时写入文档
+// 用法：xlxz-wiki bracketize [-docs wiki-docs] [-workspace 名称] [-write]
+func runBracketize(args []string) int {
+	fs := flag.NewFlagSet("bracketize", flag.ContinueOnError)
+	loadConfig := configFlags(fs)
+	workspace := fs.String("workspace", "", "处理的工作区，默认为第一个")
+	write := fs.Bool("write", false, "写入文档（默认只输出差异）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	if *write && c.ReadOnly {
+		fmt.Fprintln(os.Stderr, "只读模式下不能修改文档")
+		return 1
+	}
+	srv, err := server.New(server.Config{Config: *c, Version: Version, SkipCacheWrite: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载文档失败: %v\n", err)
+		return 1
+	}
+	changes, err := srv.Bracketize(*workspace, *write)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "处理失败: %v\n", err)
+		return 1
+	}
+	count := 0
+	for _, change := range changes {
+		if !*write {
+			fmt.Print(change.Diff())
+		}
+		count += change.Count
+	}
+	if *write {
+		fmt.Fprintf(os.Stderr, "已在 %d 个文档中加上 %d 处括号\n", len(changes), count)
+	} else {
+		fmt.Fprintf(os.Stderr, "%d 个文档中有 %d 处可以加上括号，使用 -write 写入\n", len(changes), count)
+	}
+	return 0
+}
+
+// exportBundle 写入单文件导出，失败时不留下不完整的文件
+func exportBundle(srv *server.Server, output, workspace string) error {
+	var buf bytes.Buffer
+	if err := srv.ExportBundle(&buf, workspace); err != nil {
+		return err
+	}
+	return os.WriteFile(output, buf.Bytes(), 0644)
+}
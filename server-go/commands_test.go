@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"xlxz-wiki/indexer"
+)
+
+func TestBracketize_ReadOnlyAndCache(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "冲击.md"), []byte("受击后的硬直\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "玩法.md"), []byte("释放冲击\n"), 0644)
+
+	// 只预览差异和检查不应写入索引缓存
+	if code := runBracketize([]string{"-docs", dir}); code != 0 {
+		t.Fatalf("bracketize 退出码 %d", code)
+	}
+	runLint([]string{"-docs", dir})
+	if _, err := os.Stat(filepath.Join(dir, indexer.CacheFileName)); !os.IsNotExist(err) {
+		t.Errorf("命令行检查不应写入索引缓存: %v", err)
+	}
+
+	if code := runBracketize([]string{"-docs", dir, "-readonly", "-write"}); code != 1 {
+		t.Errorf("只读模式下 -write 退出码 %d，期望 1", code)
+	}
+	if code := runLint([]string{"-docs", dir, "-readonly", "-fix"}); code != 1 {
+		t.Errorf("只读模式下 -fix 退出码 %d，期望 1", code)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "玩法.md")); string(content) != "释放冲击\n" {
+		t.Errorf("只读模式下不应修改文档: %q", content)
+	}
+
+	if code := runBracketize([]string{"-docs", dir, "-write"}); code != 0 {
+		t.Fatalf("bracketize -write 退出码 %d", code)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "玩法.md")); string(content) != "释放【冲击】\n" {
+		t.Errorf("写入结果 %q", content)
+	}
+}
@@ -0,0 +1,618 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xlxz-wiki/auth"
+	"xlxz-wiki/ignore"
+)
+
+// FileName 默认配置文件名，放在文档目录旁边
+const FileName = "xlxz-wiki.yaml"
+
+// EnvPrefix 环境变量前缀，如 XLXZ_WIKI_PORT
+const EnvPrefix = "XLXZ_WIKI_"
+
+// Config 服务器配置
+type Config struct {
+	// Path 实际加载的配置文件（没有配置文件时为空）
+	Path string
+
+	Host       string
+	Port       int
+	PortSearch int
+	// BasePath 对外访问路径前缀（加载后以 / 开头和结尾），反向代理子路径部署时如 /wiki/
+	BasePath string
+	// TrustProxy 信任 X-Forwarded-* 请求头（仅在反向代理之后开启）
+	TrustProxy bool
+	// AllowedOrigins 除同源外允许建立 WebSocket 连接的来源
+	AllowedOrigins []string
+
+	// Docs 文档目录（加载后为绝对路径）
+	Docs string
+	// Workspaces 多个文档目录，每项为 名称:目录；配置后取代 Docs
+	Workspaces []string
+	Ignore     []string
+
+	ReadOnly    bool
+	OpenBrowser bool
+
+	TLS      TLS
+	Auth     Auth
+	Features Features
+}
+
+// TLS HTTPS 配置
+type TLS struct {
+	Cert string
+	Key  string
+	// SelfSigned 证书文件不存在时自动生成自签名证书
+	SelfSigned bool
+}
+
+// Enabled 是否启用 HTTPS
+func (t TLS) Enabled() bool {
+	return t.Cert != "" || t.SelfSigned
+}
+
+// Auth 访问控制
+type Auth struct {
+	// Users 账号列表，每项为 名称:密钥:角色
+	Users []string
+	// Anonymous 未登录访问者的角色，留空时按是否配置了账号决定
+	Anonymous string
+}
+
+// Features 功能开关
+type Features struct {
+	Annotations       bool
+	DebugAPI          bool
+	AutoLink          bool // 提供 /api/links，前端据此把未加 【】 的词条也显示为可悬停的链接
+	IndexCache        bool // 索引缓存到文档目录，启动时只重新解析变化的文件
+	DefinitionLimit   int
+	ReconcileInterval time.Duration
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Host:           "127.0.0.1",
+		Port:           3055,
+		PortSearch:     10,
+		BasePath:       "/",
+		Docs:           "wiki-docs",
+		Workspaces:     []string{},
+		Ignore:         []string{},
+		AllowedOrigins: []string{},
+		Auth:           Auth{Users: []string{}},
+		OpenBrowser:    runtime.GOOS == "windows",
+		Features: Features{
+			Annotations:       true,
+			DebugAPI:          true,
+			IndexCache:        true,
+			ReconcileInterval: 30 * time.Second,
+		},
+	}
+}
+
+// field 一个配置项：同一张表驱动配置文件、环境变量、命令行参数和 config print
+type field struct {
+	key string // YAML 路径，如 listen.port
+	env string // 环境变量名（不含前缀），为空表示不支持
+	doc string
+	set func(c *Config, v any) error
+	get func(c *Config) any
+	// redact 输出配置时隐藏敏感内容
+	redact func(string) string
+	// resolve 解析其中的相对路径：配置文件中基于文件所在目录，环境变量和参数基于当前目录
+	resolve func(c *Config, baseDir string)
+}
+
+var fields = []field{
+	stringField("listen.host", "HOST", "监听地址，0.0.0.0 表示所有网卡（局域网共享时请配置 auth）", func(c *Config) *string { return &c.Host }),
+	intField("listen.port", "PORT", "起始端口", func(c *Config) *int { return &c.Port }),
+	intField("listen.portSearch", "", "端口被占用时向后尝试的个数", func(c *Config) *int { return &c.PortSearch }),
+	stringField("listen.basePath", "BASE_PATH", "对外访问路径前缀，反向代理子路径部署时如 /wiki/", func(c *Config) *string { return &c.BasePath }),
+	boolField("listen.trustProxy", "TRUST_PROXY", "信任 X-Forwarded-For/Host/Proto/Prefix 请求头，仅在反向代理之后开启", func(c *Config) *bool { return &c.TrustProxy }),
+	listField("listen.allowedOrigins", "ALLOWED_ORIGINS", "除同源外允许连接 WebSocket 的来源，如 https://wiki.example.com", func(c *Config) *[]string { return &c.AllowedOrigins }),
+	pathField("tls.cert", "TLS_CERT", "HTTPS 证书文件", func(c *Config) *string { return &c.TLS.Cert }),
+	pathField("tls.key", "TLS_KEY", "HTTPS 私钥文件", func(c *Config) *string { return &c.TLS.Key }),
+	boolField("tls.selfSigned", "TLS_SELF_SIGNED", "证书不存在时自动生成自签名证书（默认保存在文档目录旁）", func(c *Config) *bool { return &c.TLS.SelfSigned }),
+	pathField("docs", "DOCS", "文档目录，相对路径基于配置文件所在目录", func(c *Config) *string { return &c.Docs }),
+	workspaceField("workspaces", "WORKSPACES", "多个文档目录：名称:目录，第一个为默认工作区；配置后不再使用 docs", func(c *Config) *[]string { return &c.Workspaces }),
+	listField("ignore", "IGNORE", "忽略的路径（glob，** 匹配任意层目录）", func(c *Config) *[]string { return &c.Ignore }),
+	boolField("readOnly", "READONLY", "只读模式，拒绝所有修改请求", func(c *Config) *bool { return &c.ReadOnly }),
+	boolField("openBrowser", "OPEN_BROWSER", "启动后自动打开浏览器", func(c *Config) *bool { return &c.OpenBrowser }),
+	withRedact(listField("auth.users", "AUTH_USERS", "账号列表：名称:密钥:角色，角色为 viewer / reviewer / editor，密钥可写成 sha256:<hex>", func(c *Config) *[]string { return &c.Auth.Users }), auth.Redact),
+	stringField("auth.anonymous", "AUTH_ANONYMOUS", "未登录访问者的角色，留空时：未配置账号为 viewer（允许匿名编辑需设为 editor），否则需要登录", func(c *Config) *string { return &c.Auth.Anonymous }),
+	boolField("features.annotations", "", "审校批注接口", func(c *Config) *bool { return &c.Features.Annotations }),
+	boolField("features.debugApi", "", "调试接口 /api/debug/index", func(c *Config) *bool { return &c.Features.DebugAPI }),
+	boolField("features.indexCache", "", "索引缓存到文档目录中的 .xlxz-wiki-index.json，启动时只重新解析变化的文件", func(c *Config) *bool { return &c.Features.IndexCache }),
+	boolField("features.autoLink", "", "自动链接未加 【】 的词条（/api/links）", func(c *Config) *bool { return &c.Features.AutoLink }),
+	intField("features.definitionLimit", "", "悬停卡片定义的字数上限，0 表示不限", func(c *Config) *int { return &c.Features.DefinitionLimit }),
+	durationField("features.reconcileInterval", "", "定期对账扫描间隔，0 表示关闭", func(c *Config) *time.Duration { return &c.Features.ReconcileInterval }),
+}
+
+// Options 加载选项
+type Options struct {
+	// Path 显式指定的配置文件（-config），为空时自动查找
+	Path string
+	// Flags 命令行中显式设置的配置项：YAML 路径 → 值
+	Flags map[string]string
+	// Getenv 读取环境变量，默认 os.Getenv
+	Getenv func(string) string
+	// WorkDir 相对路径的基准目录，默认当前目录
+	WorkDir string
+}
+
+// Load 按 默认值 < 配置文件 < 环境变量 < 命令行参数 的优先级加载并校验配置
+func Load(opts Options) (*Config, error) {
+	if opts.Getenv == nil {
+		opts.Getenv = os.Getenv
+	}
+	if opts.WorkDir == "" {
+		opts.WorkDir, _ = os.Getwd()
+	}
+
+	c := Default()
+	c.Docs = filepath.Join(opts.WorkDir, c.Docs)
+
+	path, explicit := opts.Path, opts.Path != ""
+	if !explicit {
+		path = opts.Getenv(EnvPrefix + "CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		path = findConfigFile(opts)
+	}
+	if path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(opts.WorkDir, path)
+		}
+		if err := c.loadFile(path); err != nil {
+			if explicit || !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if v := opts.Getenv(EnvPrefix + f.env); v != "" {
+			if err := c.apply(f, v, opts.WorkDir); err != nil {
+				return nil, fmt.Errorf("环境变量 %s%s: %w", EnvPrefix, f.env, err)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(opts.Flags))
+	for key := range opts.Flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f, ok := lookup(key)
+		if !ok {
+			return nil, fmt.Errorf("未知配置项 %s", key)
+		}
+		if err := c.apply(f, opts.Flags[key], opts.WorkDir); err != nil {
+			return nil, fmt.Errorf("参数 %s: %w", key, err)
+		}
+	}
+
+	c.normalize()
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// normalize 补全派生的默认值
+func (c *Config) normalize() {
+	c.BasePath = NormalizeBasePath(c.BasePath)
+	if c.TLS.SelfSigned && c.TLS.Cert == "" && c.TLS.Key == "" {
+		dir := filepath.Dir(c.Docs)
+		c.TLS.Cert = filepath.Join(dir, "xlxz-wiki.crt")
+		c.TLS.Key = filepath.Join(dir, "xlxz-wiki.key")
+	}
+}
+
+// NormalizeBasePath 规范化路径前缀：以 / 开头和结尾，空值为 /
+func NormalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return "/"
+	}
+	return "/" + p + "/"
+}
+
+// findConfigFile 在文档目录旁边（其上级目录）和当前目录查找默认配置文件
+func findConfigFile(opts Options) string {
+	docs := opts.Flags["docs"]
+	if docs == "" {
+		docs = opts.Getenv(EnvPrefix + "DOCS")
+	}
+	if docs == "" {
+		docs = "wiki-docs"
+	}
+	if !filepath.IsAbs(docs) {
+		docs = filepath.Join(opts.WorkDir, docs)
+	}
+
+	for _, dir := range []string{filepath.Dir(docs), opts.WorkDir} {
+		candidate := filepath.Join(dir, FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tree, err := parseYAML(string(content))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]any)
+	flatten("", tree, values)
+	for key, v := range values {
+		f, ok := lookup(key)
+		if !ok {
+			return fmt.Errorf("%s: 未知配置项 %s", path, key)
+		}
+		if err := c.apply(f, v, filepath.Dir(path)); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+	}
+	c.Path = path
+	return nil
+}
+
+// apply 设置配置项；路径类配置项的相对路径基于 baseDir 解析
+func (c *Config) apply(f field, v any, baseDir string) error {
+	if err := f.set(c, v); err != nil {
+		return err
+	}
+	if f.resolve != nil {
+		f.resolve(c, baseDir)
+	}
+	return nil
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("listen.port 必须在 1-65535 之间，当前为 %d", c.Port))
+	}
+	if c.PortSearch < 1 {
+		errs = append(errs, fmt.Errorf("listen.portSearch 至少为 1，当前为 %d", c.PortSearch))
+	}
+	if c.Host != "" && c.Host != "localhost" && net.ParseIP(c.Host) == nil {
+		errs = append(errs, fmt.Errorf("listen.host 不是有效的 IP 地址: %s", c.Host))
+	}
+	if strings.ContainsAny(c.BasePath, "?#") {
+		errs = append(errs, fmt.Errorf("listen.basePath 只能是路径: %s", c.BasePath))
+	}
+	for _, origin := range c.AllowedOrigins {
+		if u, err := url.Parse(origin); origin != "*" && (err != nil || u.Scheme == "" || u.Host == "" || u.Path != "") {
+			errs = append(errs, fmt.Errorf("listen.allowedOrigins 应为 scheme://host[:port] 或 *: %s", origin))
+		}
+	}
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		errs = append(errs, errors.New("tls.cert 和 tls.key 需要同时配置"))
+	}
+	if c.TLS.Cert != "" && !c.TLS.SelfSigned {
+		for _, file := range []string{c.TLS.Cert, c.TLS.Key} {
+			if _, err := os.Stat(file); err != nil {
+				errs = append(errs, fmt.Errorf("tls: %w", err))
+			}
+		}
+	}
+	if c.Docs == "" {
+		errs = append(errs, errors.New("docs 不能为空"))
+	}
+	if _, err := c.WorkspaceList(); err != nil {
+		errs = append(errs, fmt.Errorf("workspaces: %w", err))
+	}
+	if _, err := ignore.New(c.Ignore); err != nil {
+		errs = append(errs, fmt.Errorf("ignore: %w", err))
+	}
+	if _, err := auth.New(c.Auth.Users, c.Auth.Anonymous); err != nil {
+		errs = append(errs, fmt.Errorf("auth: %w", err))
+	}
+	if c.Features.DefinitionLimit < 0 {
+		errs = append(errs, errors.New("features.definitionLimit 不能为负数"))
+	}
+	if c.Features.ReconcileInterval < 0 {
+		errs = append(errs, errors.New("features.reconcileInterval 不能为负数"))
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultWorkspace 只配置 docs 时唯一工作区的名称
+const DefaultWorkspace = "default"
+
+// Workspace 一个命名的文档目录
+type Workspace struct {
+	Name string
+	Docs string
+}
+
+// WorkspaceList 解析工作区列表，第一个为默认工作区；未配置 workspaces 时只有 docs 一个
+// 名称出现在 URL 路径（/w/名称/）和词条引用中，不能包含 / ? # % 或冒号
+func (c *Config) WorkspaceList() ([]Workspace, error) {
+	if len(c.Workspaces) == 0 {
+		return []Workspace{{Name: DefaultWorkspace, Docs: c.Docs}}, nil
+	}
+	var list []Workspace
+	seen := make(map[string]bool)
+	for _, entry := range c.Workspaces {
+		name, dir, ok := strings.Cut(entry, ":")
+		name, dir = strings.TrimSpace(name), strings.TrimSpace(dir)
+		if !ok || name == "" || dir == "" {
+			return nil, fmt.Errorf("应为 名称:目录: %q", entry)
+		}
+		if strings.ContainsAny(name, "/?#%") {
+			return nil, fmt.Errorf("名称不能包含 / ? # %%: %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("重复的工作区 %s", name)
+		}
+		seen[name] = true
+		list = append(list, Workspace{Name: name, Docs: dir})
+	}
+	return list, nil
+}
+
+// IgnoreMatcher 返回忽略路径匹配器（配置已校验，不会出错）
+func (c *Config) IgnoreMatcher() *ignore.Matcher {
+	m, _ := ignore.New(c.Ignore)
+	return m
+}
+
+// Authenticator 返回访问控制（配置已校验，不会出错）
+func (c *Config) Authenticator() *auth.Authenticator {
+	a, _ := auth.New(c.Auth.Users, c.Auth.Anonymous)
+	return a
+}
+
+// Loopback 是否只监听本机地址
+func (c *Config) Loopback() bool {
+	if c.Host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(c.Host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Addr 返回监听地址
+func (c *Config) Addr(port int) string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// WriteYAML 以配置文件格式输出生效的配置（config print）
+func (c *Config) WriteYAML(w io.Writer) error {
+	var b strings.Builder
+	if c.Path != "" {
+		fmt.Fprintf(&b, "# 配置文件: %s\n", c.Path)
+	} else {
+		b.WriteString("# 未找到配置文件，使用默认值\n")
+	}
+
+	section := ""
+	for _, f := range fields {
+		name, indent := f.key, ""
+		if i := strings.Index(f.key, "."); i >= 0 {
+			if f.key[:i] != section {
+				section = f.key[:i]
+				fmt.Fprintf(&b, "%s:\n", section)
+			}
+			name, indent = f.key[i+1:], "  "
+		} else {
+			section = ""
+		}
+
+		fmt.Fprintf(&b, "%s# %s\n", indent, f.doc)
+		switch v := f.get(c).(type) {
+		case []string:
+			if len(v) == 0 {
+				fmt.Fprintf(&b, "%s%s: []\n", indent, name)
+				continue
+			}
+			fmt.Fprintf(&b, "%s%s:\n", indent, name)
+			for _, item := range v {
+				if f.redact != nil {
+					item = f.redact(item)
+				}
+				fmt.Fprintf(&b, "%s  - %s\n", indent, quoteYAML(item))
+			}
+		case string:
+			fmt.Fprintf(&b, "%s%s: %s\n", indent, name, quoteYAML(v))
+		default:
+			fmt.Fprintf(&b, "%s%s: %v\n", indent, name, v)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func lookup(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func flatten(prefix string, tree map[string]any, out map[string]any) {
+	for key, v := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// ─── 配置项类型 ─────────────────────────────────────────────
+
+func pathField(key, env, doc string, ptr func(*Config) *string) field {
+	f := stringField(key, env, doc, ptr)
+	f.resolve = func(c *Config, baseDir string) {
+		*ptr(c) = resolvePath(*ptr(c), baseDir)
+	}
+	return f
+}
+
+// workspaceField 名称:目录 列表，目录按来源解析相对路径
+func workspaceField(key, env, doc string, ptr func(*Config) *[]string) field {
+	f := listField(key, env, doc, ptr)
+	f.resolve = func(c *Config, baseDir string) {
+		for i, entry := range *ptr(c) {
+			if name, dir, ok := strings.Cut(entry, ":"); ok {
+				(*ptr(c))[i] = name + ":" + resolvePath(strings.TrimSpace(dir), baseDir)
+			}
+		}
+	}
+	return f
+}
+
+func resolvePath(p, baseDir string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(baseDir, p)
+}
+
+func withRedact(f field, redact func(string) string) field {
+	f.redact = redact
+	return f
+}
+
+func scalar(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.New("应为单个值")
+	}
+	return strings.TrimSpace(s), nil
+}
+
+func stringField(key, env, doc string, ptr func(*Config) *string) field {
+	return field{key: key, env: env, doc: doc,
+		set: func(c *Config, v any) error {
+			s, err := scalar(v)
+			*ptr(c) = s
+			return err
+		},
+		get: func(c *Config) any { return *ptr(c) },
+	}
+}
+
+func intField(key, env, doc string, ptr func(*Config) *int) field {
+	return field{key: key, env: env, doc: doc,
+		set: func(c *Config, v any) error {
+			s, err := scalar(v)
+			if err != nil {
+				return err
+			}
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("应为整数: %q", s)
+			}
+			*ptr(c) = n
+			return nil
+		},
+		get: func(c *Config) any { return *ptr(c) },
+	}
+}
+
+func boolField(key, env, doc string, ptr func(*Config) *bool) field {
+	return field{key: key, env: env, doc: doc,
+		set: func(c *Config, v any) error {
+			s, err := scalar(v)
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(s) {
+			case "true", "yes", "on", "1":
+				*ptr(c) = true
+			case "false", "no", "off", "0":
+				*ptr(c) = false
+			default:
+				return fmt.Errorf("应为 true 或 false: %q", s)
+			}
+			return nil
+		},
+		get: func(c *Config) any { return *ptr(c) },
+	}
+}
+
+func durationField(key, env, doc string, ptr func(*Config) *time.Duration) field {
+	return field{key: key, env: env, doc: doc,
+		set: func(c *Config, v any) error {
+			s, err := scalar(v)
+			if err != nil {
+				return err
+			}
+			if s == "0" {
+				*ptr(c) = 0
+				return nil
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("应为时长（如 30s、5m）: %q", s)
+			}
+			*ptr(c) = d
+			return nil
+		},
+		get: func(c *Config) any { return *ptr(c) },
+	}
+}
+
+// listField 列表项；环境变量和命令行中用逗号分隔
+func listField(key, env, doc string, ptr func(*Config) *[]string) field {
+	return field{key: key, env: env, doc: doc,
+		set: func(c *Config, v any) error {
+			switch v := v.(type) {
+			case []string:
+				*ptr(c) = v
+			case string:
+				var items []string
+				for _, item := range strings.Split(v, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						items = append(items, item)
+					}
+				}
+				*ptr(c) = items
+			default:
+				return errors.New("应为列表")
+			}
+			return nil
+		},
+		get: func(c *Config) any { return *ptr(c) },
+	}
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestLoadDefaults(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Load(Options{WorkDir: dir, Getenv: env(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Path != "" {
+		t.Errorf("Path = %q, want empty", c.Path)
+	}
+	if c.Port != 3055 || c.PortSearch != 10 {
+		t.Errorf("port = %d/%d", c.Port, c.PortSearch)
+	}
+	if c.Docs != filepath.Join(dir, "wiki-docs") {
+		t.Errorf("Docs = %q", c.Docs)
+	}
+	if !c.Features.Annotations || c.Features.ReconcileInterval != 30*time.Second {
+		t.Errorf("features = %+v", c.Features)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), `
+listen:
+  host: 127.0.0.1
+  port: 4000   # 注释
+docs: ./文档
+ignore: [drafts/**, "*.tmp.md"]
+features:
+  debugApi: false
+  reconcileInterval: 1m
+`)
+
+	c, err := Load(Options{
+		WorkDir: dir,
+		Getenv:  env(map[string]string{EnvPrefix + "PORT": "5000", EnvPrefix + "READONLY": "true"}),
+		Flags:   map[string]string{"listen.port": "6000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Path != filepath.Join(dir, FileName) {
+		t.Errorf("Path = %q", c.Path)
+	}
+	if c.Host != "127.0.0.1" {
+		t.Errorf("Host = %q", c.Host)
+	}
+	if c.Port != 6000 {
+		t.Errorf("Port = %d, want flag value 6000", c.Port)
+	}
+	if !c.ReadOnly {
+		t.Error("ReadOnly should come from env")
+	}
+	if c.Docs != filepath.Join(dir, "文档") {
+		t.Errorf("Docs = %q", c.Docs)
+	}
+	if strings.Join(c.Ignore, "|") != "drafts/**|*.tmp.md" {
+		t.Errorf("Ignore = %q", c.Ignore)
+	}
+	if c.Features.DebugAPI || c.Features.ReconcileInterval != time.Minute {
+		t.Errorf("features = %+v", c.Features)
+	}
+}
+
+func TestLoadFindsFileNextToDocs(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "project")
+	os.MkdirAll(filepath.Join(project, "wiki-docs"), 0755)
+	writeFile(t, filepath.Join(project, FileName), "listen:\n  port: 4100\n")
+
+	c, err := Load(Options{
+		WorkDir: dir,
+		Getenv:  env(nil),
+		Flags:   map[string]string{"docs": "project/wiki-docs"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 4100 {
+		t.Errorf("Port = %d, config next to docs not loaded", c.Port)
+	}
+	if c.Docs != filepath.Join(project, "wiki-docs") {
+		t.Errorf("Docs = %q", c.Docs)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "listen:\n  prot: 1\n", "未知配置项 listen.prot"},
+		{"bad int", "listen:\n  port: abc\n", "应为整数"},
+		{"port range", "listen:\n  port: 70000\n", "listen.port"},
+		{"bad bool", "readOnly: maybe\n", "true 或 false"},
+		{"bad duration", "features:\n  reconcileInterval: soon\n", "应为时长"},
+		{"bad pattern", "ignore:\n  - \"[\"\n", "无效的忽略模式"},
+		{"bad host", "listen:\n  host: example.com\n", "listen.host"},
+		{"tab indent", "listen:\n\tport: 1\n", "Tab"},
+		{"bad origin", "listen:\n  allowedOrigins: [wiki.example.com]\n", "allowedOrigins"},
+		{"tls half", "tls:\n  cert: a.crt\n", "同时配置"},
+		{"tls missing file", "tls:\n  cert: a.crt\n  key: a.key\n", "a.crt"},
+		{"workspace format", "workspaces: [项目A]\n", "名称:目录"},
+		{"workspace name", "workspaces: [\"a/b:docs\"]\n", "名称不能包含"},
+		{"workspace duplicate", "workspaces: [a:x, a:y]\n", "重复的工作区"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "test.yaml")
+			writeFile(t, path, tt.content)
+			_, err := Load(Options{Path: path, WorkDir: dir, Getenv: env(nil)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMissingExplicitFile(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Load(Options{Path: "missing.yaml", WorkDir: dir, Getenv: env(nil)})
+	if err == nil {
+		t.Fatal("explicit missing config should be an error")
+	}
+}
+
+func TestWriteYAMLRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := Load(Options{
+		WorkDir: dir,
+		Getenv:  env(map[string]string{EnvPrefix + "IGNORE": "drafts/**, #临时/*"}),
+	})
+
+	var b strings.Builder
+	if err := c.WriteYAML(&b); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "printed.yaml")
+	writeFile(t, path, b.String())
+
+	again, err := Load(Options{Path: path, WorkDir: dir, Getenv: env(nil)})
+	if err != nil {
+		t.Fatalf("printed config does not load: %v\n%s", err, b.String())
+	}
+	if strings.Join(again.Ignore, "|") != "drafts/**|#临时/*" {
+		t.Errorf("Ignore = %q", again.Ignore)
+	}
+	if again.Features != c.Features || again.Port != c.Port || again.Docs != c.Docs {
+		t.Errorf("round trip mismatch:\n%+v\n%+v", again, c)
+	}
+}
+
+func TestLoadBasePathAndTLS(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), `
+listen:
+  basePath: wiki
+tls:
+  selfSigned: true
+`)
+	c, err := Load(Options{WorkDir: dir, Getenv: env(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BasePath != "/wiki/" {
+		t.Errorf("BasePath = %q", c.BasePath)
+	}
+	if !c.TLS.Enabled() || c.TLS.Cert != filepath.Join(dir, "xlxz-wiki.crt") || c.TLS.Key != filepath.Join(dir, "xlxz-wiki.key") {
+		t.Errorf("TLS = %+v", c.TLS)
+	}
+}
+
+func TestLoadWorkspaces(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), `
+workspaces:
+  - 项目A:./a/wiki-docs
+  - 通用术语:/srv/glossary
+`)
+	c, err := Load(Options{WorkDir: dir, Getenv: env(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := c.WorkspaceList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Workspace{
+		{Name: "项目A", Docs: filepath.Join(dir, "a", "wiki-docs")},
+		{Name: "通用术语", Docs: "/srv/glossary"},
+	}
+	if len(list) != len(want) || list[0] != want[0] || list[1] != want[1] {
+		t.Errorf("WorkspaceList = %+v, want %+v", list, want)
+	}
+
+	// 未配置 workspaces 时只有 docs
+	c, _ = Load(Options{WorkDir: t.TempDir(), Getenv: env(nil)})
+	if list, _ := c.WorkspaceList(); len(list) != 1 || list[0].Name != DefaultWorkspace || list[0].Docs != c.Docs {
+		t.Errorf("WorkspaceList = %+v", list)
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 配置文件只需要 YAML 的一个子集：嵌套映射、字符串列表、标量和注释。
+// 与 indexer 解析 frontmatter 的方式一致，这里手写解析，避免引入依赖。
+
+type yamlLine struct {
+	num    int // 1-based 行号，用于错误信息
+	indent int
+	text   string
+}
+
+// parseYAML 把 YAML 文本解析为嵌套的 map[string]any / []string / string
+func parseYAML(text string) (map[string]any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		leading := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		if strings.Contains(leading, "\t") && strings.TrimSpace(raw) != "" {
+			return nil, fmt.Errorf("第 %d 行: 不支持用 Tab 缩进", i+1)
+		}
+		content := strings.TrimRight(stripComment(raw), " ")
+		trimmed := strings.TrimLeft(content, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(content) - len(trimmed), text: trimmed})
+	}
+
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+	p := &yamlParser{lines: lines}
+	value, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("第 %d 行: 缩进不正确", p.lines[p.pos].num)
+	}
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("配置文件顶层必须是键值映射")
+	}
+	return m, nil
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseBlock 解析同一缩进层级的映射或列表
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if strings.HasPrefix(p.lines[p.pos].text, "- ") || p.lines[p.pos].text == "-" {
+		return p.parseList(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseList(indent int) ([]string, error) {
+	items := []string{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("第 %d 行: 缩进不正确", line.num)
+		}
+		if !strings.HasPrefix(line.text, "-") {
+			return nil, fmt.Errorf("第 %d 行: 列表中混入了键值对", line.num)
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if isMapEntry(item) {
+			return nil, fmt.Errorf("第 %d 行: 列表项只支持字符串", line.num)
+		}
+		items = append(items, unquote(item))
+		p.pos++
+	}
+	return items, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("第 %d 行: 缩进不正确", line.num)
+		}
+
+		key, value, ok := splitMapEntry(line.text)
+		if !ok {
+			return nil, fmt.Errorf("第 %d 行: 无法解析 %q", line.num, line.text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("第 %d 行: 重复的配置项 %s", line.num, key)
+		}
+		p.pos++
+
+		switch {
+		case value != "":
+			m[key] = parseScalarOrFlowList(value)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			nested, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = nested
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text, "-"):
+			// 列表项与键同级缩进（YAML 允许的写法）
+			list, err := p.parseList(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = list
+		default:
+			m[key] = ""
+		}
+	}
+	return m, nil
+}
+
+// splitMapEntry 拆分 "key: value"，value 可以为空
+func splitMapEntry(text string) (string, string, bool) {
+	i := strings.Index(text, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	if i+1 < len(text) && text[i+1] != ' ' {
+		return "", "", false
+	}
+	return unquote(strings.TrimSpace(text[:i])), strings.TrimSpace(text[i+1:]), true
+}
+
+func isMapEntry(text string) bool {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		return false
+	}
+	_, _, ok := splitMapEntry(text)
+	return ok
+}
+
+// parseScalarOrFlowList 支持 [a, b] 形式的行内列表
+func parseScalarOrFlowList(value string) any {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		items := []string{}
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, unquote(item))
+			}
+		}
+		return items
+	}
+	return unquote(value)
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 {
+		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
+
+// stripComment 去掉行尾注释（引号内的 # 保留）
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// quoteYAML 输出时给需要的字符串加引号
+func quoteYAML(s string) string {
+	if s != "" && !strings.ContainsAny(s, ":#'\"[]{},&*!|>%@`") &&
+		!strings.HasPrefix(s, "-") && strings.TrimSpace(s) == s {
+		return s
+	}
+	// 不支持转义，含双引号时改用单引号
+	if strings.Contains(s, `"`) {
+		return "'" + s + "'"
+	}
+	return `"` + s + `"`
+}
@@ -0,0 +1,87 @@
+package ignore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Matcher 忽略路径匹配器（类 .gitignore 的简化规则）
+//
+//   - 不含 / 的模式匹配任意层级的文件名或目录名，如 *.tmp.md、草稿
+//   - 含 / 的模式从文档根目录开始匹配，如 drafts/*.md、/private
+//   - ** 匹配任意层目录，如 **/归档/**
+//   - 目录被忽略时，其下所有文件一并忽略
+//
+// nil Matcher 不忽略任何路径
+type Matcher struct {
+	patterns [][]string
+}
+
+// New 编译忽略模式，模式语法错误时返回错误
+func New(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		p = strings.TrimSuffix(toSlash(p), "/")
+		anchored := strings.Contains(p, "/")
+		p = strings.TrimPrefix(p, "/")
+		segments := strings.Split(p, "/")
+		for _, seg := range segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("无效的忽略模式 %q: %v", p, err)
+			}
+		}
+		if !anchored && segments[0] != "**" {
+			// 不含 / 的模式匹配任意层级
+			segments = []string{"**", segments[0]}
+		}
+		m.patterns = append(m.patterns, segments)
+	}
+	return m, nil
+}
+
+// Match 判断相对路径（/ 分隔）是否被忽略
+func (m *Matcher) Match(relPath string) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+	parts := strings.Split(strings.Trim(toSlash(relPath), "/"), "/")
+	// 路径本身或任一上级目录匹配都算忽略
+	for n := 1; n <= len(parts); n++ {
+		for _, p := range m.patterns {
+			if matchSegments(p, parts[:n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
+}
+
+func toSlash(p string) string {
+	return strings.ReplaceAll(p, "\\", "/")
+}
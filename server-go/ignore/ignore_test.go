@@ -0,0 +1,41 @@
+package ignore
+
+import "testing"
+
+func TestMatcher(t *testing.T) {
+	m, err := New([]string{"*.tmp.md", "草稿", "drafts/*.md", "**/归档/**", "/private/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"a.tmp.md", true},
+		{"角色系统/b.tmp.md", true},
+		{"草稿", true},
+		{"角色系统/草稿/滑移.md", true},
+		{"drafts/a.md", true},
+		{"drafts/sub/a.md", false},
+		{"sub/drafts/a.md", false},
+		{"角色系统/归档/旧.md", true},
+		{"private/secret.md", true},
+		{"sub/private/secret.md", false},
+		{"角色系统/滑移.md", false},
+		{"README.md", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	var nilMatcher *Matcher
+	if nilMatcher.Match("a.md") {
+		t.Error("nil Matcher 不应忽略任何路径")
+	}
+	if _, err := New([]string{"[未闭合"}); err == nil {
+		t.Error("无效模式应返回错误")
+	}
+}
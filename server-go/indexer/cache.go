@@ -0,0 +1,155 @@
+package indexer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CacheFileName 索引缓存文件名，放在文档目录中（隐藏文件，不出现在文件树中，也不被监听）
+const CacheFileName = ".xlxz-wiki-index.json"
+
+// cacheVersion 缓存格式版本，解析规则或缓存结构变化时递增，旧版本的缓存整体作废
+const cacheVersion = 1
+
+// indexCache 缓存文件的内容：各文件的磁盘状态和解析结果
+type indexCache struct {
+	Version int `json:"version"`
+	// Limit 解析时的定义字数上限，与当前设置不同时缓存作废
+	Limit int                   `json:"limit"`
+	Files map[string]*cachedDoc `json:"files"`
+}
+
+// cachedDoc 一个文件的缓存记录
+type cachedDoc struct {
+	FileState
+	Scope    string         `json:"scope,omitempty"`
+	Terms    []*WikiTerm    `json:"terms,omitempty"`
+	Formulas []*WikiFormula `json:"formulas,omitempty"`
+	Outline  []*OutlineItem `json:"outline,omitempty"`
+	Tags     []string       `json:"tags,omitempty"`
+	Status   string         `json:"status,omitempty"`
+}
+
+func (c *cachedDoc) docInfo() *docInfo {
+	return &docInfo{
+		scope:    c.Scope,
+		terms:    c.Terms,
+		formulas: c.Formulas,
+		outline:  c.Outline,
+		tags:     c.Tags,
+		status:   c.Status,
+	}
+}
+
+// SetCache 设置索引缓存文件，path 为空时不使用缓存（需在 BuildIndex 前调用）
+// BuildIndex 时未变化的文件直接使用缓存的解析结果，SaveCache 写回
+func (w *WikiIndexer) SetCache(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cachePath = path
+}
+
+// loadCache 读取缓存中各文件的记录，没有缓存、无法读取或已作废时返回 nil
+func (w *WikiIndexer) loadCache() map[string]*cachedDoc {
+	if w.cachePath == "" {
+		return nil
+	}
+	cache, err := readCache(w.cachePath)
+	if err != nil || cache.Limit != w.limit {
+		return nil
+	}
+	return cache.Files
+}
+
+// SaveCache 将索引写入缓存文件；未设置缓存或上次保存后索引没有变化时不写入
+// 先写临时文件再重命名，读取缓存的工具不会读到写了一半的文件
+func (w *WikiIndexer) SaveCache() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cachePath == "" || !w.dirty {
+		return nil
+	}
+
+	cache := indexCache{Version: cacheVersion, Limit: w.limit, Files: make(map[string]*cachedDoc, len(w.files))}
+	for relPath, state := range w.files {
+		doc := w.docs[relPath]
+		cache.Files[relPath] = &cachedDoc{
+			FileState: state,
+			Scope:     doc.scope,
+			Terms:     doc.terms,
+			Formulas:  doc.formulas,
+			Outline:   doc.outline,
+			Tags:      doc.tags,
+			Status:    doc.status,
+		}
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	tmp := w.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, w.cachePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+func readCache(path string) (*indexCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cache indexCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, err
+	}
+	if cache.Version != cacheVersion {
+		return nil, errors.New("索引缓存版本不匹配")
+	}
+	return &cache, nil
+}
+
+// ReadCache 从文档目录中的缓存文件读取索引，不解析文档，供离线工具使用；
+// 缓存反映最后一次保存时的文档，之后的修改不会体现
+func ReadCache(rootDir string) (*WikiIndex, error) {
+	cache, err := readCache(filepath.Join(rootDir, CacheFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	w := New(rootDir)
+	relPaths := make([]string, 0, len(cache.Files))
+	for relPath := range cache.Files {
+		relPaths = append(relPaths, relPath)
+	}
+	// 按路径逐级排序，与 BuildIndex 遍历目录的顺序一致，同名定义的先后不变
+	sort.Slice(relPaths, func(i, j int) bool {
+		return slices.Compare(strings.Split(relPaths[i], "/"), strings.Split(relPaths[j], "/")) < 0
+	})
+
+	scopeSet := make(map[string]bool)
+	for _, relPath := range relPaths {
+		c := cache.Files[relPath]
+		if scope := w.addDoc(relPath, c.FileState, c.docInfo()); scope != "" {
+			scopeSet[scope] = true
+		}
+	}
+	for scope := range scopeSet {
+		w.index.Scopes = append(w.index.Scopes, scope)
+	}
+	if info, err := os.Stat(filepath.Join(rootDir, CacheFileName)); err == nil {
+		w.index.BuildTime = info.ModTime().UnixMilli()
+	}
+	return w.index, nil
+}
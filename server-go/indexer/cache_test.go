@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache_WarmStart(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, CacheFileName)
+	write := func(name, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	write("战斗/冲击.md", "---\nscope: 战斗\ntags: [PvP]\n---\n# 冲击\n冲击定义\n")
+	write("滑移.md", "滑移定义\n")
+	write("翻滚.md", "翻滚定义\n")
+	write("背包.md", "背包定义\n")
+
+	idx := New(dir)
+	idx.SetCache(cachePath)
+	idx.BuildIndex()
+	if err := idx.SaveCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 篡改缓存中的定义：没有重新解析的文件会保留篡改后的内容
+	var cache map[string]any
+	data, _ := os.ReadFile(cachePath)
+	json.Unmarshal(data, &cache)
+	for _, doc := range cache["files"].(map[string]any) {
+		for _, term := range doc.(map[string]any)["terms"].([]any) {
+			term.(map[string]any)["definition"] = "缓存"
+		}
+	}
+	data, _ = json.Marshal(cache)
+	os.WriteFile(cachePath, data, 0644)
+
+	write("滑移.md", "滑移的新定义\n")
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "翻滚.md"), later, later) // 只有 mtime 变化
+	os.Remove(filepath.Join(dir, "背包.md"))
+	write("冲刺.md", "冲刺定义\n")
+
+	warm := New(dir)
+	warm.SetCache(cachePath)
+	warm.BuildIndex()
+	index := warm.GetIndex()
+	for name, want := range map[string]string{"冲击": "缓存", "翻滚": "缓存", "滑移": "滑移的新定义", "冲刺": "冲刺定义"} {
+		if defs := index.Terms[name]; len(defs) != 1 || defs[0].Definition != want {
+			t.Errorf("%s = %+v，期望定义 %q", name, defs, want)
+		}
+	}
+	if _, ok := index.Terms["背包"]; ok {
+		t.Error("已删除文件的词条应被移除")
+	}
+	if outline, _ := warm.Outline("战斗/冲击.md"); len(outline) != 1 || outline[0].Text != "冲击" {
+		t.Errorf("缓存的大纲 = %+v", outline)
+	}
+	if !reflect.DeepEqual(index.Scopes, []string{"战斗"}) || !reflect.DeepEqual(warm.FileTags("战斗/冲击.md"), []string{"PvP"}) {
+		t.Errorf("scopes = %v, tags = %v", index.Scopes, warm.FileTags("战斗/冲击.md"))
+	}
+
+	if err := warm.SaveCache(); err != nil {
+		t.Fatal(err)
+	}
+	offline, err := ReadCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(offline.Terms, index.Terms) || !reflect.DeepEqual(offline.Scopes, index.Scopes) {
+		t.Errorf("ReadCache 与索引不一致: %+v", offline.Terms)
+	}
+
+	// 定义字数上限变化后缓存作废，全部重新解析
+	limited := New(dir)
+	limited.SetCache(cachePath)
+	limited.SetDefinitionLimit(10)
+	limited.BuildIndex()
+	if defs := limited.GetIndex().Terms["冲击"]; len(defs) != 1 || defs[0].Definition == "缓存" {
+		t.Errorf("上限变化后仍使用了缓存: %+v", defs)
+	}
+}
@@ -0,0 +1,103 @@
+package indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// FileDrift 磁盘与索引记录不一致的文件（相对路径）
+type FileDrift struct {
+	Added    []string
+	Modified []string
+	Removed  []string
+}
+
+// Paths 返回所有需要重新索引的文件
+func (d FileDrift) Paths() []string {
+	paths := make([]string, 0, len(d.Added)+len(d.Modified)+len(d.Removed))
+	paths = append(paths, d.Added...)
+	paths = append(paths, d.Modified...)
+	return append(paths, d.Removed...)
+}
+
+// Drift 对比磁盘上的 .md 文件与索引器最后看到的状态
+// 只有 mtime 变化而内容相同的文件不算漂移，仅刷新记录的 mtime
+func (w *WikiIndexer) Drift() FileDrift {
+	w.mu.RLock()
+	known := make(map[string]FileState, len(w.files))
+	for relPath, state := range w.files {
+		known[relPath] = state
+	}
+	w.mu.RUnlock()
+
+	var drift FileDrift
+	touched := make(map[string]FileState)
+	seen := make(map[string]bool)
+
+	filepath.Walk(w.rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		relPath, _ := filepath.Rel(w.rootDir, path)
+		relPath = filepath.ToSlash(relPath)
+		if path != w.rootDir && w.ignore.Match(relPath) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		seen[relPath] = true
+
+		state, ok := known[relPath]
+		if !ok {
+			drift.Added = append(drift.Added, relPath)
+			return nil
+		}
+		modTime := info.ModTime().UnixNano()
+		if state.ModTime == modTime && state.Size == info.Size() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		if hashContent(content) != state.Hash {
+			drift.Modified = append(drift.Modified, relPath)
+			return nil
+		}
+		state.ModTime = modTime
+		touched[relPath] = state
+		return nil
+	})
+
+	for relPath := range known {
+		if !seen[relPath] {
+			drift.Removed = append(drift.Removed, relPath)
+		}
+	}
+
+	if len(touched) > 0 {
+		w.mu.Lock()
+		for relPath, state := range touched {
+			// 扫描期间文件可能已被重新索引，只在哈希仍一致时刷新
+			if current, ok := w.files[relPath]; ok && current.Hash == state.Hash {
+				w.files[relPath] = state
+			}
+		}
+		w.mu.Unlock()
+	}
+
+	return drift
+}
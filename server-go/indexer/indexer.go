@@ -3,20 +3,43 @@ package indexer
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"xlxz-wiki/ignore"
 )
 
 // WikiTerm 词条定义
 type WikiTerm struct {
-	Term           string   `json:"term"`
-	Aliases        []string `json:"aliases"`
-	Definition     string   `json:"definition"`
-	Scope          string   `json:"scope"`
-	FilePath       string   `json:"filePath"`
-	DefinitionType string   `json:"definitionType"`
-	HasMore        bool     `json:"hasMore,omitempty"`
+	Term       string   `json:"term"`
+	Aliases    []string `json:"aliases"`
+	Definition string   `json:"definition"`
+	// DefinitionText 定义的纯文本，设置了字数上限时截断
+	DefinitionText string `json:"definitionText,omitempty"`
+	Scope          string `json:"scope"`
+	FilePath       string `json:"filePath"`
+	DefinitionType string `json:"definitionType"`
+	HasMore        bool   `json:"hasMore,omitempty"`
+	// Line 定义所在行，目前只有章节定义记录
+	Line int `json:"line,omitempty"`
+	// Tags 所在文档 frontmatter 中的标签
+	Tags []string `json:"tags,omitempty"`
+	// Status 所在文档的状态：draft、review、approved、deprecated，未设置时为空
+	Status string `json:"status,omitempty"`
+	// Deprecated、ReplacedBy 文件词条已废弃，引用应改为 ReplacedBy
+	Deprecated bool   `json:"deprecated,omitempty"`
+	ReplacedBy string `json:"replacedBy,omitempty"`
+	// ForbiddenSynonyms 文件词条禁止使用的同义说法（frontmatter synonyms-forbidden），正文中出现时应改用词条
+	ForbiddenSynonyms []string `json:"forbiddenSynonyms,omitempty"`
+	// Section、Anchor、EndLine 章节定义的标题、锚点和最后一行
+	Section string `json:"section,omitempty"`
+	Anchor  string `json:"anchor,omitempty"`
+	EndLine int    `json:"endLine,omitempty"`
+	// Workspace 来自其他工作区时为该工作区名称
+	Workspace string `json:"workspace,omitempty"`
 }
 
 // WikiFormula 策划公式
@@ -34,19 +57,49 @@ type WikiIndex struct {
 	Formulas  map[string][]*WikiFormula `json:"formulas"`
 	Scopes    []string                  `json:"scopes"`
 	BuildTime int64                     `json:"buildTime"`
+	// External 只读来源的词条：工作区名称 → 别名 → 定义，用于 【工作区:词条】 引用
+	External map[string]map[string][]*WikiTerm `json:"external,omitempty"`
+}
+
+// FileState 索引器最后一次解析文件时的磁盘状态，用于发现漏掉的变更
+type FileState struct {
+	ModTime int64  `json:"modTime"`
+	Size    int64  `json:"size"`
+	Hash    string `json:"hash"`
+}
+
+// docInfo 文件的解析结果：词条、公式和大纲、标签、状态等文件级别的信息
+type docInfo struct {
+	scope    string
+	terms    []*WikiTerm
+	formulas []*WikiFormula
+	outline  []*OutlineItem
+	tags     []string
+	status   string
 }
 
 // WikiIndexer 索引器
 type WikiIndexer struct {
 	rootDir string
 	index   *WikiIndex
-	mu      sync.RWMutex
+	files   map[string]FileState
+	// docs 各文件的解析结果，与 files 同步增删
+	docs    map[string]*docInfo
+	ignore  *ignore.Matcher
+	sources []source
+	limit   int // 定义纯文本的字数上限
+	// cachePath 索引缓存文件，为空时不使用缓存；dirty 表示索引在上次保存缓存后有变化
+	cachePath string
+	dirty     bool
+	mu        sync.RWMutex
 }
 
 // New 创建索引器
 func New(rootDir string) *WikiIndexer {
 	return &WikiIndexer{
 		rootDir: rootDir,
+		files:   make(map[string]FileState),
+		docs:    make(map[string]*docInfo),
 		index: &WikiIndex{
 			Terms:    make(map[string][]*WikiTerm),
 			Formulas: make(map[string][]*WikiFormula),
@@ -55,50 +108,71 @@ func New(rootDir string) *WikiIndexer {
 	}
 }
 
-// BuildIndex 构建索引
+// SetIgnore 设置忽略路径，被忽略的文件不参与索引（需在 BuildIndex 前调用）
+func (w *WikiIndexer) SetIgnore(m *ignore.Matcher) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ignore = m
+}
+
+// SetDefinitionLimit 设置定义纯文本（definitionText）的字数上限，0 表示不限（需在 BuildIndex 前调用）
+// 超过上限时定义只保留前面完整的块
+func (w *WikiIndexer) SetDefinitionLimit(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.limit = n
+}
+
+// Ignored 判断相对路径是否被配置忽略
+func (w *WikiIndexer) Ignored(relPath string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.ignore.Match(relPath)
+}
+
+// BuildIndex 构建索引；设置了缓存时，mtime 和大小或内容哈希与缓存一致的文件直接使用缓存的解析结果
 func (w *WikiIndexer) BuildIndex() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	cached := w.loadCache()
+	w.dirty = len(cached) == 0
+
 	w.index = &WikiIndex{
 		Terms:    make(map[string][]*WikiTerm),
 		Formulas: make(map[string][]*WikiFormula),
 		Scopes:   []string{},
 	}
 
+	w.files = make(map[string]FileState)
+	w.docs = make(map[string]*docInfo)
+
 	scopeSet := make(map[string]bool)
 
 	err := filepath.Walk(w.rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
 		}
-		if info.IsDir() || !strings.HasSuffix(path, ".md") {
-			return nil
-		}
-
 		relPath, _ := filepath.Rel(w.rootDir, path)
 		relPath = filepath.ToSlash(relPath)
-
-		terms, formulas, scope := ParseFile(path, relPath)
-
-		if scope != "" {
-			scopeSet[scope] = true
-		}
-
-		for _, term := range terms {
-			for _, alias := range term.Aliases {
-				w.index.Terms[alias] = append(w.index.Terms[alias], term)
+		if path != w.rootDir && w.ignore.Match(relPath) {
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil
 		}
-
-		for _, formula := range formulas {
-			for _, cv := range formula.CalculatedValues {
-				w.index.Formulas[cv] = append(w.index.Formulas[cv], formula)
-			}
+		if info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
 		}
 
+		if scope := w.indexFile(relPath, info, cached[relPath]); scope != "" {
+			scopeSet[scope] = true
+		}
 		return nil
 	})
+	if len(w.files) != len(cached) {
+		w.dirty = true // 有文件被删除
+	}
 
 	for scope := range scopeSet {
 		w.index.Scopes = append(w.index.Scopes, scope)
@@ -112,47 +186,135 @@ func (w *WikiIndexer) BuildIndex() error {
 func (w *WikiIndexer) UpdateFile(relPath string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	w.updateFile(relPath)
+	w.index.BuildTime = time.Now().UnixMilli()
+}
+
+// UpdateFiles 批量更新文件索引：存在的文件重新解析，已删除的文件移除
+// 整批只加锁一次、只遍历一次词条表移除旧条目、只更新一次 BuildTime
+func (w *WikiIndexer) UpdateFiles(relPaths []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	batch := make(map[string]bool, len(relPaths))
+	for _, relPath := range relPaths {
+		batch[relPath] = true
+	}
+	w.removeEntries(func(filePath string) bool { return batch[filePath] })
+	for _, relPath := range relPaths {
+		if batch[relPath] {
+			delete(batch, relPath) // 同一批中重复的路径只解析一次
+			w.reindexFile(relPath)
+		}
+	}
+	w.index.BuildTime = time.Now().UnixMilli()
+}
+
+// FilesIn 返回目录下（含子目录）已被索引的文件路径
+func (w *WikiIndexer) FilesIn(relDir string) []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	prefix := strings.TrimSuffix(relDir, "/") + "/"
+	var files []string
+	for relPath := range w.files {
+		if strings.HasPrefix(relPath, prefix) {
+			files = append(files, relPath)
+		}
+	}
+	return files
+}
+
+func (w *WikiIndexer) updateFile(relPath string) {
+	w.removeEntries(func(filePath string) bool { return filePath == relPath })
+	w.reindexFile(relPath)
+}
 
-	// 移除该文件的旧条目
-	w.removeFileEntries(relPath)
+// reindexFile 重新解析已移除旧条目的文件
+func (w *WikiIndexer) reindexFile(relPath string) {
+	if w.ignore.Match(relPath) {
+		return
+	}
 
 	// 重新解析
-	fullPath := filepath.Join(w.rootDir, relPath)
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	info, err := os.Stat(filepath.Join(w.rootDir, relPath))
+	if err != nil {
 		return // 文件已删除
 	}
+	w.indexFile(relPath, info, nil)
+}
 
-	terms, formulas, _ := ParseFile(fullPath, relPath)
+// indexFile 读取并解析文件，加入索引并记录文件状态，返回文件的 scope
+// cached 为缓存中该文件的记录，文件未变化时不再读取或解析
+func (w *WikiIndexer) indexFile(relPath string, info os.FileInfo, cached *cachedDoc) string {
+	state := FileState{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+	if cached != nil && cached.ModTime == state.ModTime && cached.Size == state.Size {
+		return w.addDoc(relPath, cached.FileState, cached.docInfo())
+	}
+
+	content, err := os.ReadFile(filepath.Join(w.rootDir, relPath))
+	if err != nil {
+		return ""
+	}
+	state.Hash = hashContent(content)
+	w.dirty = true
+	if cached != nil && cached.Hash == state.Hash {
+		return w.addDoc(relPath, state, cached.docInfo()) // 只有 mtime 变化
+	}
 
-	for _, term := range terms {
+	doc := parseDocument(string(content), relPath, w.limit)
+	return w.addDoc(relPath, state, &docInfo{
+		scope:    doc.scope,
+		terms:    doc.terms,
+		formulas: doc.formulas,
+		outline:  buildOutline(doc.headings),
+		tags:     doc.tags,
+		status:   doc.status,
+	})
+}
+
+// addDoc 将文件的解析结果加入索引，返回文件的 scope
+func (w *WikiIndexer) addDoc(relPath string, state FileState, doc *docInfo) string {
+	w.files[relPath] = state
+	w.docs[relPath] = doc
+
+	for _, term := range doc.terms {
 		for _, alias := range term.Aliases {
 			w.index.Terms[alias] = append(w.index.Terms[alias], term)
 		}
 	}
 
-	for _, formula := range formulas {
+	for _, formula := range doc.formulas {
 		for _, cv := range formula.CalculatedValues {
 			w.index.Formulas[cv] = append(w.index.Formulas[cv], formula)
 		}
 	}
 
-	w.index.BuildTime = time.Now().UnixMilli()
+	return doc.scope
 }
 
 // RemoveFile 移除文件的索引
 func (w *WikiIndexer) RemoveFile(relPath string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	w.removeFileEntries(relPath)
+	w.removeEntries(func(filePath string) bool { return filePath == relPath })
 	w.index.BuildTime = time.Now().UnixMilli()
 }
 
-func (w *WikiIndexer) removeFileEntries(relPath string) {
+// removeEntries 移除来源文件满足 match 的词条、公式和文件状态
+func (w *WikiIndexer) removeEntries(match func(filePath string) bool) {
+	w.dirty = true
+	for relPath := range w.files {
+		if match(relPath) {
+			delete(w.files, relPath)
+			delete(w.docs, relPath)
+		}
+	}
+
 	// 移除词条
 	for alias, terms := range w.index.Terms {
 		var filtered []*WikiTerm
 		for _, t := range terms {
-			if t.FilePath != relPath {
+			if !match(t.FilePath) {
 				filtered = append(filtered, t)
 			}
 		}
@@ -167,7 +329,7 @@ func (w *WikiIndexer) removeFileEntries(relPath string) {
 	for name, formulas := range w.index.Formulas {
 		var filtered []*WikiFormula
 		for _, f := range formulas {
-			if f.FilePath != relPath {
+			if !match(f.FilePath) {
 				filtered = append(filtered, f)
 			}
 		}
@@ -179,19 +341,67 @@ func (w *WikiIndexer) removeFileEntries(relPath string) {
 	}
 }
 
-// GetIndex 获取索引
+// Files 返回已索引的文件路径，按路径排序
+func (w *WikiIndexer) Files() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	files := make([]string, 0, len(w.files))
+	for relPath := range w.files {
+		files = append(files, relPath)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// FileCount 返回已索引的文件数
+func (w *WikiIndexer) FileCount() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.files)
+}
+
+// GetIndex 返回索引的快照，之后的文件变更不会修改返回的索引，调用方也不应修改它
 func (w *WikiIndexer) GetIndex() *WikiIndex {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
-	return w.index
+	return w.index.snapshot()
+}
+
+// TermCount 返回词条别名数
+func (w *WikiIndexer) TermCount() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.index.Terms)
+}
+
+// BuildTime 返回索引最后更新的时间（毫秒）
+func (w *WikiIndexer) BuildTime() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.index.BuildTime
+}
+
+// snapshot 复制词条表和公式表；各别名的定义列表只会被整体替换或在末尾追加，可以共用
+func (index *WikiIndex) snapshot() *WikiIndex {
+	copied := *index
+	copied.Terms = make(map[string][]*WikiTerm, len(index.Terms))
+	for alias, defs := range index.Terms {
+		copied.Terms[alias] = defs[:len(defs):len(defs)]
+	}
+	copied.Formulas = make(map[string][]*WikiFormula, len(index.Formulas))
+	for name, formulas := range index.Formulas {
+		copied.Formulas[name] = formulas[:len(formulas):len(formulas)]
+	}
+	copied.Scopes = slices.Clone(index.Scopes)
+	return &copied
 }
 
-// Search 搜索词条
-func (w *WikiIndexer) Search(query string) []*WikiTerm {
+// Search 搜索词条；指定 tags 时只返回所在文档带有全部这些标签的词条，此时 query 可以为空
+func (w *WikiIndexer) Search(query string, tags ...string) []*WikiTerm {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	if query == "" {
+	if query == "" && len(tags) == 0 {
 		return nil
 	}
 
@@ -203,7 +413,7 @@ func (w *WikiIndexer) Search(query string) []*WikiTerm {
 		if strings.Contains(strings.ToLower(alias), query) {
 			for _, t := range terms {
 				key := t.FilePath + ":" + t.Term
-				if !seen[key] {
+				if !seen[key] && hasTags(t.Tags, tags) {
 					seen[key] = true
 					results = append(results, t)
 				}
@@ -0,0 +1,38 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	write("冲击.md", "冲击定义\n")
+	write("滑移.md", "滑移定义\n【施法者】：发起者\n")
+	write("翻滚.md", "翻滚定义\n")
+	idx := New(dir)
+	idx.BuildIndex()
+	before := idx.GetIndex()
+
+	write("冲击.md", "冲击的新定义\n")
+	os.Remove(filepath.Join(dir, "滑移.md"))
+	write("冲刺.md", "冲刺定义\n【施法者】：冲刺者\n")
+	idx.UpdateFiles([]string{"冲击.md", "滑移.md", "冲刺.md", "冲击.md"})
+
+	index := idx.GetIndex()
+	for name, want := range map[string]string{"冲击": "冲击的新定义", "翻滚": "翻滚定义", "冲刺": "冲刺定义", "施法者": "冲刺者"} {
+		if defs := index.Terms[name]; len(defs) != 1 || defs[0].Definition != want {
+			t.Errorf("%s = %+v，期望定义 %q", name, defs, want)
+		}
+	}
+	if _, ok := index.Terms["滑移"]; ok {
+		t.Error("已删除文件的词条应被移除")
+	}
+	if defs := before.Terms["冲击"]; len(defs) != 1 || defs[0].Definition != "冲击定义" {
+		t.Errorf("更新前取得的快照被修改: %+v", defs)
+	}
+}
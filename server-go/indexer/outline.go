@@ -0,0 +1,43 @@
+package indexer
+
+import "xlxz-wiki/markdown"
+
+// OutlineItem 文档大纲中的一个标题，下级标题在 Children 中
+type OutlineItem struct {
+	Level    int            `json:"level"`
+	Text     string         `json:"text"`
+	Slug     string         `json:"slug"`
+	Line     int            `json:"line"`
+	Children []*OutlineItem `json:"children,omitempty"`
+}
+
+// Outline 返回已索引文件的大纲，随索引一起更新；文件未被索引时 ok 为 false
+func (w *WikiIndexer) Outline(relPath string) (items []*OutlineItem, ok bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	doc, ok := w.docs[relPath]
+	if !ok {
+		return nil, false
+	}
+	return doc.outline, true
+}
+
+// buildOutline 按级别把标题组织成树，跳级的标题挂在最近的上级标题下
+func buildOutline(headings []markdown.Heading) []*OutlineItem {
+	var roots []*OutlineItem
+	var stack []*OutlineItem
+	for _, h := range headings {
+		item := &OutlineItem{Level: h.Level, Text: h.Text, Slug: h.Slug, Line: h.Line}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, item)
+		}
+		stack = append(stack, item)
+	}
+	return roots
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"regexp"
 	"strings"
+
+	"xlxz-wiki/markdown"
 )
 
 var (
@@ -29,8 +31,27 @@ func ParseFile(fullPath, relPath string) ([]*WikiTerm, []*WikiFormula, string) {
 	if err != nil {
 		return nil, nil, ""
 	}
+	return ParseContent(string(content), relPath)
+}
+
+// ParseContent 解析 Markdown 文本，relPath 用于确定文件词条名
+func ParseContent(text, relPath string) ([]*WikiTerm, []*WikiFormula, string) {
+	doc := parseDocument(text, relPath, 0)
+	return doc.terms, doc.formulas, doc.scope
+}
+
+// document 单个文件的解析结果
+type document struct {
+	terms    []*WikiTerm
+	formulas []*WikiFormula
+	scope    string
+	headings []markdown.Heading // 用于生成大纲
+	tags     []string
+	status   string
+}
 
-	text := string(content)
+// parseDocument limit 为定义纯文本的字数上限，0 表示不限
+func parseDocument(text, relPath string, limit int) *document {
 	var terms []*WikiTerm
 	var formulas []*WikiFormula
 	var scope string
@@ -59,21 +80,39 @@ func ParseFile(fullPath, relPath string) ([]*WikiTerm, []*WikiFormula, string) {
 		}
 	}
 
-	// 提取定义（支持 <!-- more --> 截断）
-	definition, hasMore := extractDefinition(text)
+	// 提取定义（支持 <!-- more --> 截断和 frontmatter summary）
+	def := extractDefinition(text, fm["summary"], limit)
 
-	if definition != "" {
+	// 已废弃的词条即使没有正文也要索引，引用时转到 replacedBy
+	deprecated := strings.EqualFold(strings.TrimSpace(fm["deprecated"]), "true")
+	var replacedBy string
+	if deprecated {
+		replacedBy = strings.Trim(strings.TrimSpace(fm["replacedBy"]), `"'【】`)
+	}
+	if def.Markdown != "" || deprecated {
 		terms = append(terms, &WikiTerm{
-			Term:           term,
-			Aliases:        aliases,
-			Definition:     definition,
-			Scope:          scope,
-			FilePath:       relPath,
-			DefinitionType: "file",
-			HasMore:        hasMore,
+			Term:              term,
+			Aliases:           aliases,
+			Definition:        def.Markdown,
+			DefinitionText:    def.Text,
+			Scope:             scope,
+			FilePath:          relPath,
+			DefinitionType:    "file",
+			HasMore:           def.HasMore,
+			Deprecated:        deprecated,
+			ReplacedBy:        replacedBy,
+			ForbiddenSynonyms: splitList(fm["synonyms-forbidden"]),
 		})
 	}
 
+	// 章节定义：【词条#章节】，带 {.term} 的标题还可直接以标题引用
+	sections := markdown.Sections(text, limit)
+	terms = append(terms, sectionTerms(sections, term, aliases, scope, relPath)...)
+	headings := make([]markdown.Heading, len(sections))
+	for i, s := range sections {
+		headings[i] = s.Heading
+	}
+
 	// 解析文件内定义
 	matches := inlineDefRe.FindAllStringSubmatch(text, -1)
 	for _, m := range matches {
@@ -83,6 +122,7 @@ func ParseFile(fullPath, relPath string) ([]*WikiTerm, []*WikiFormula, string) {
 			Term:           termName,
 			Aliases:        []string{termName},
 			Definition:     def,
+			DefinitionText: markdown.Summarize(def, limit).Text,
 			Scope:          scope,
 			FilePath:       relPath,
 			DefinitionType: "inline",
@@ -115,7 +155,70 @@ func ParseFile(fullPath, relPath string) ([]*WikiTerm, []*WikiFormula, string) {
 		}
 	}
 
-	return terms, formulas, scope
+	// 文档的标签和状态同样适用于其中定义的词条
+	tags := splitList(fm["tags"])
+	status := documentStatus(fm)
+	for _, t := range terms {
+		t.Tags = tags
+		t.Status = status
+	}
+
+	return &document{terms: terms, formulas: formulas, scope: scope, headings: headings, tags: tags, status: status}
+}
+
+// splitList 解析 frontmatter 中的列表：a, b、[a, b] 或 YAML 数组，去掉引号和重复项
+func splitList(value string) []string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var items []string
+	seen := make(map[string]bool)
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' }) {
+		item = strings.Trim(strings.TrimSpace(item), `"'`)
+		if item != "" && !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sectionTerms 为每个标题创建章节定义，名称为 文件词条#标题 和 文件词条#锚点（各别名同样适用）
+func sectionTerms(sections []markdown.Section, term string, aliases []string, scope, relPath string) []*WikiTerm {
+	var terms []*WikiTerm
+	for _, h := range sections {
+		var names []string
+		for _, a := range aliases {
+			names = append(names, a+"#"+h.Text)
+			if h.Slug != h.Text {
+				names = append(names, a+"#"+h.Slug)
+			}
+		}
+		if h.Term && h.Text != term {
+			names = append(names, h.Text)
+		}
+
+		def := h.Summary
+		terms = append(terms, &WikiTerm{
+			Term:           term + "#" + h.Text,
+			Aliases:        names,
+			Definition:     def.Markdown,
+			DefinitionText: def.Text,
+			Scope:          scope,
+			FilePath:       relPath,
+			DefinitionType: "section",
+			HasMore:        def.HasMore,
+			Line:           h.Line,
+			Section:        h.Text,
+			Anchor:         h.Slug,
+			EndLine:        h.End,
+		})
+	}
+	return terms
+}
+
+// ParseScope 读取文档 frontmatter 中的 scope
+func ParseScope(text string) string {
+	return parseFrontmatter(text)["scope"]
 }
 
 func parseFrontmatter(text string) map[string]string {
@@ -158,38 +261,14 @@ func parseFrontmatter(text string) map[string]string {
 	return fm
 }
 
-// moreTagRe 匹配 <!-- more --> 标记
-var moreTagRe = regexp.MustCompile(`(?i)^\s*<!--\s*more\s*-->\s*$`)
-
-// extractDefinition 提取定义内容，支持 <!-- more --> 截断
-// 返回定义内容和是否有更多内容
-func extractDefinition(text string) (string, bool) {
-	// 移除 frontmatter
-	text = frontmatterRe.ReplaceAllString(text, "")
-	text = strings.ReplaceAll(text, "\r\n", "\n")
-	
-	lines := strings.Split(text, "\n")
-	var contentLines []string
-	hasMore := false
-	
-	for _, line := range lines {
-		// 检查 <!-- more --> 标记
-		if moreTagRe.MatchString(line) {
-			hasMore = true
-			break
-		}
-		
-		trimmed := strings.TrimSpace(line)
-		// 跳过标题、分隔线、空行和内联定义
-		if trimmed == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(trimmed, "---") {
-			continue
-		}
-		if inlineDefRe.MatchString(line) {
-			continue
-		}
-		
-		contentLines = append(contentLines, line)
+// extractDefinition 提取文件定义：开头到 <!-- more --> 之间的完整块，跳过标题和文件内定义
+// frontmatter 中的 summary 优先，此时正文都算作更多内容
+func extractDefinition(text, summary string, limit int) markdown.Summary {
+	summary = strings.Trim(strings.TrimSpace(summary), `"'`)
+	if summary == "" {
+		return markdown.Summarize(text, limit)
 	}
-	
-	return strings.TrimSpace(strings.Join(contentLines, "\n")), hasMore
+	def := markdown.Summarize(summary, limit)
+	def.HasMore = true
+	return def
 }
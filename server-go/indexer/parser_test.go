@@ -0,0 +1,78 @@
+package indexer
+
+import "testing"
+
+func TestParseContent_Definition(t *testing.T) {
+	fileTerm := func(terms []*WikiTerm) *WikiTerm {
+		for _, term := range terms {
+			if term.DefinitionType == "file" {
+				return term
+			}
+		}
+		return nil
+	}
+
+	terms, _, _ := ParseContent("# 冲击\n\n受击后的**硬直**。\n\n<!-- more -->\n\n细节\n", "冲击.md")
+	term := fileTerm(terms)
+	if term == nil || term.Definition != "受击后的**硬直**。" || term.DefinitionText != "受击后的硬直。" || !term.HasMore {
+		t.Errorf("文件定义 = %+v", term)
+	}
+
+	terms, _, _ = ParseContent("---\nsummary: \"受击后的短暂硬直\"\n---\n很长的正文\n", "冲击.md")
+	term = fileTerm(terms)
+	if term == nil || term.Definition != "受击后的短暂硬直" || !term.HasMore {
+		t.Errorf("summary 覆盖 = %+v", term)
+	}
+
+	terms = parseDocument("第一段。\n\n第二段很长很长很长。\n", "冲击.md", 6).terms
+	term = fileTerm(terms)
+	if term == nil || term.Definition != "第一段。" || !term.HasMore {
+		t.Errorf("字数上限 = %+v", term)
+	}
+}
+
+func TestParseContent_Sections(t *testing.T) {
+	src := "---\nalias: Impact\n---\n# 冲击\n\n受击后的硬直。\n\n## 判定规则 {.term}\n\n按韧性判定。\n\n## Hit Rules {#hit}\n\n细节\n"
+	terms, _, _ := ParseContent(src, "战斗/冲击.md")
+
+	sections := make(map[string]*WikiTerm)
+	for _, term := range terms {
+		if term.DefinitionType == "section" {
+			sections[term.Term] = term
+		}
+	}
+	if len(sections) != 3 {
+		t.Fatalf("章节定义 = %d 个", len(sections))
+	}
+
+	rule := sections["冲击#判定规则"]
+	if rule == nil || rule.Definition != "按韧性判定。" || rule.Line != 8 || rule.EndLine != 10 || rule.Anchor != "判定规则" || rule.Section != "判定规则" {
+		t.Fatalf("冲击#判定规则 = %+v", rule)
+	}
+	want := []string{"冲击#判定规则", "Impact#判定规则", "判定规则"}
+	if len(rule.Aliases) != len(want) {
+		t.Fatalf("别名 = %v, want %v", rule.Aliases, want)
+	}
+	for i := range want {
+		if rule.Aliases[i] != want[i] {
+			t.Errorf("别名 = %v, want %v", rule.Aliases, want)
+		}
+	}
+
+	hit := sections["冲击#Hit Rules"]
+	if hit == nil || hit.Anchor != "hit" || len(hit.Aliases) != 4 || hit.Aliases[1] != "冲击#hit" {
+		t.Errorf("冲击#Hit Rules = %+v", hit)
+	}
+}
+
+func TestParseContent_Deprecated(t *testing.T) {
+	terms, _, _ := ParseContent("---\ndeprecated: true\nreplacedBy: 【拆除建筑】\n---\n", "删除建筑.md")
+	if len(terms) != 1 || !terms[0].Deprecated || terms[0].ReplacedBy != "拆除建筑" {
+		t.Errorf("没有正文的废弃词条 = %+v", terms)
+	}
+
+	terms, _, _ = ParseContent("---\nreplacedBy: 拆除建筑\n---\n正文\n", "删除建筑.md")
+	if len(terms) != 1 || terms[0].Deprecated || terms[0].ReplacedBy != "" {
+		t.Errorf("未声明 deprecated 时 replacedBy 不生效: %+v", terms)
+	}
+}
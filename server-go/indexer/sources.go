@@ -0,0 +1,58 @@
+package indexer
+
+// source 其他工作区的索引，作为只读词条来源
+type source struct {
+	name string
+	idx  *WikiIndexer
+}
+
+// AddSource 添加只读词条来源，文档中以 【name:词条】 引用其中的词条
+// 来源的索引由其所属工作区维护，这里只读取（需在启动监听前调用）
+func (w *WikiIndexer) AddSource(name string, src *WikiIndexer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sources = append(w.sources, source{name: name, idx: src})
+}
+
+// IndexWithSources 返回索引及各只读来源的词条（/api/index 和全量重建推送使用）
+// 来源的词条为副本，Workspace 标记为来源名称
+func (w *WikiIndexer) IndexWithSources() *WikiIndex {
+	w.mu.RLock()
+	index := w.index.snapshot()
+	sources := w.sources
+	w.mu.RUnlock()
+
+	if len(sources) == 0 {
+		return index
+	}
+	// 释放自身的锁后再读取来源，两个工作区互为来源时不会互相等待
+	index.External = make(map[string]map[string][]*WikiTerm, len(sources))
+	for _, src := range sources {
+		index.External[src.name] = src.idx.termsAs(src.name)
+	}
+	return index
+}
+
+// termsAs 复制词条表，并将每个定义标记为来自 workspace
+func (w *WikiIndexer) termsAs(workspace string) map[string][]*WikiTerm {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	copies := make(map[*WikiTerm]*WikiTerm)
+	terms := make(map[string][]*WikiTerm, len(w.index.Terms))
+	for alias, defs := range w.index.Terms {
+		list := make([]*WikiTerm, len(defs))
+		for i, def := range defs {
+			c, ok := copies[def]
+			if !ok {
+				dup := *def
+				dup.Workspace = workspace
+				c = &dup
+				copies[def] = c
+			}
+			list[i] = c
+		}
+		terms[alias] = list
+	}
+	return terms
+}
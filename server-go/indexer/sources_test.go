@@ -0,0 +1,39 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexWithSources(t *testing.T) {
+	shared, project := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(shared, "滑移.md"), []byte("---\nalias:\n  - 基础移动\n---\n\n通用的滑移定义\n"), 0644)
+	os.WriteFile(filepath.Join(project, "冲击.md"), []byte("项目内的冲击定义\n"), 0644)
+
+	glossary := New(shared)
+	glossary.BuildIndex()
+	idx := New(project)
+	idx.BuildIndex()
+	idx.AddSource("通用术语", glossary)
+
+	index := idx.IndexWithSources()
+	if len(index.Terms["滑移"]) != 0 {
+		t.Error("来源的词条不应混入本工作区的词条表")
+	}
+	defs := index.External["通用术语"]["基础移动"]
+	if len(defs) != 1 || defs[0].FilePath != "滑移.md" || defs[0].Workspace != "通用术语" {
+		t.Fatalf("External = %+v", index.External)
+	}
+	if index.External["通用术语"]["滑移"][0] != defs[0] {
+		t.Error("同一定义的多个别名应指向同一副本")
+	}
+
+	// 来源自身的索引不被标记
+	if glossary.GetIndex().Terms["滑移"][0].Workspace != "" {
+		t.Error("来源的原始词条被修改")
+	}
+	if glossary.IndexWithSources().External != nil {
+		t.Error("没有来源时不应输出 external")
+	}
+}
@@ -0,0 +1,137 @@
+package indexer
+
+import (
+	"slices"
+	"strings"
+)
+
+// 文档状态，frontmatter 中的 status 字段
+const (
+	StatusDraft      = "draft"
+	StatusReview     = "review"
+	StatusApproved   = "approved"
+	StatusDeprecated = "deprecated"
+)
+
+// statusTransitions 各状态可以变更到的状态：草稿 → 审核中 → 已定稿 → 已废弃，
+// 审核不通过退回草稿，定稿后修改重新送审。未设置状态的文档可以进入任意状态
+var statusTransitions = map[string][]string{
+	"":               {StatusDraft, StatusReview, StatusApproved, StatusDeprecated},
+	StatusDraft:      {StatusReview},
+	StatusReview:     {StatusApproved, StatusDraft},
+	StatusApproved:   {StatusDeprecated, StatusReview},
+	StatusDeprecated: {},
+}
+
+// ValidStatus 判断是否为已知的文档状态（不含空状态）
+func ValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok && status != ""
+}
+
+// NextStatuses 返回 from 可以变更到的状态
+func NextStatuses(from string) []string {
+	return statusTransitions[from]
+}
+
+// CanTransition 判断文档状态能否从 from 变更为 to
+func CanTransition(from, to string) bool {
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// ParseStatus 读取文档 frontmatter 中的状态，未设置或无法识别时为空
+func ParseStatus(text string) string {
+	return documentStatus(parseFrontmatter(text))
+}
+
+func documentStatus(fm map[string]string) string {
+	status := strings.ToLower(strings.Trim(strings.TrimSpace(fm["status"]), `"'`))
+	if !ValidStatus(status) {
+		return ""
+	}
+	return status
+}
+
+// FileStatus 返回已索引文件的状态
+func (w *WikiIndexer) FileStatus(relPath string) string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if doc, ok := w.docs[relPath]; ok {
+		return doc.status
+	}
+	return ""
+}
+
+// WithoutStatus 返回去掉指定状态文档中词条的索引副本，用于隐藏草稿、已废弃的定义
+func (index *WikiIndex) WithoutStatus(statuses ...string) *WikiIndex {
+	filter := func(terms map[string][]*WikiTerm) map[string][]*WikiTerm {
+		filtered := make(map[string][]*WikiTerm, len(terms))
+		for alias, defs := range terms {
+			var kept []*WikiTerm
+			for _, def := range defs {
+				if def.Status == "" || !slices.Contains(statuses, def.Status) {
+					kept = append(kept, def)
+				}
+			}
+			if len(kept) > 0 {
+				filtered[alias] = kept
+			}
+		}
+		return filtered
+	}
+
+	copied := *index
+	copied.Terms = filter(index.Terms)
+	if index.External != nil {
+		copied.External = make(map[string]map[string][]*WikiTerm, len(index.External))
+		for name, terms := range index.External {
+			copied.External[name] = filter(terms)
+		}
+	}
+	return &copied
+}
+
+// SetFrontmatter 设置 frontmatter 中的字段，value 为空时删除该字段；
+// 原有的值（包括 YAML 数组的各行）整体替换，没有 frontmatter 时在文件开头添加
+func SetFrontmatter(text, key, value string) string {
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+	field := key + ": " + value
+
+	m := frontmatterRe.FindStringSubmatchIndex(text)
+	if m == nil {
+		if value == "" {
+			return text
+		}
+		return "---" + newline + field + newline + "---" + newline + text
+	}
+
+	var lines []string
+	found, skipping := false, false
+	for _, l := range strings.Split(strings.ReplaceAll(text[m[2]:m[3]], "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(l)
+		if skipping && trimmed != "" && (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t") || strings.HasPrefix(trimmed, "- ")) {
+			continue // 原值的后续行
+		}
+		skipping = false
+		if name, _, ok := strings.Cut(l, ":"); ok && strings.TrimSpace(name) == key && !strings.HasPrefix(l, " ") {
+			found, skipping = true, true
+			if value != "" {
+				lines = append(lines, field)
+			}
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if !found && value != "" {
+		lines = append(lines, field)
+	}
+	if len(lines) == 0 {
+		// 字段全部删除后去掉空的 frontmatter
+		rest := strings.TrimPrefix(strings.TrimPrefix(text[m[1]:], "\r"), "\n")
+		return rest
+	}
+	return text[:m[2]] + strings.Join(lines, newline) + text[m[3]:]
+}
@@ -0,0 +1,55 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetFrontmatter(t *testing.T) {
+	tests := []struct {
+		name, text, key, value, want string
+	}{
+		{"新增 frontmatter", "正文\n", "status", "draft", "---\nstatus: draft\n---\n正文\n"},
+		{"追加字段", "---\nscope: 战斗\n---\n正文", "status", "review", "---\nscope: 战斗\nstatus: review\n---\n正文"},
+		{"替换字段", "---\nstatus: draft\nscope: 战斗\n---\n正文", "status", "review", "---\nstatus: review\nscope: 战斗\n---\n正文"},
+		{"替换数组", "---\ntags:\n  - a\n  - b\nscope: 战斗\n---\n", "tags", "[c]", "---\ntags: [c]\nscope: 战斗\n---\n"},
+		{"保留 CRLF", "---\r\nstatus: draft\r\n---\r\n正文", "status", "review", "---\r\nstatus: review\r\n---\r\n正文"},
+		{"删除字段", "---\nstatus: draft\nscope: 战斗\n---\n正文", "status", "", "---\nscope: 战斗\n---\n正文"},
+		{"删除最后一个字段", "---\nstatus: draft\n---\n正文", "status", "", "正文"},
+	}
+	for _, tt := range tests {
+		if got := SetFrontmatter(tt.text, tt.key, tt.value); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	if !CanTransition("", StatusApproved) || !CanTransition(StatusDraft, StatusReview) || !CanTransition(StatusReview, StatusDraft) {
+		t.Error("允许的流转被拒绝")
+	}
+	if CanTransition(StatusDraft, StatusApproved) || CanTransition(StatusDeprecated, StatusDraft) || CanTransition(StatusDraft, "") {
+		t.Error("不允许的流转被接受")
+	}
+	if ParseStatus("---\nstatus: \"Draft\"\n---\n") != StatusDraft || ParseStatus("---\nstatus: 定稿\n---\n") != "" {
+		t.Error("ParseStatus 结果不符")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "冲击.md"), []byte("---\nstatus: draft\n---\n草稿定义\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "滑移.md"), []byte("滑移定义\n【施法者】：发起者\n"), 0644)
+	idx := New(dir)
+	idx.BuildIndex()
+
+	if idx.FileStatus("冲击.md") != StatusDraft || idx.GetIndex().Terms["冲击"][0].Status != StatusDraft {
+		t.Error("索引中的状态不符")
+	}
+	index := idx.GetIndex().WithoutStatus(StatusDraft, StatusDeprecated)
+	if len(index.Terms["冲击"]) != 0 || len(index.Terms["滑移"]) != 1 || len(index.Terms["施法者"]) != 1 {
+		t.Errorf("WithoutStatus 结果不符: %v", index.Terms)
+	}
+	if len(idx.GetIndex().Terms["冲击"]) != 1 {
+		t.Error("WithoutStatus 不应修改原索引")
+	}
+}
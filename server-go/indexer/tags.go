@@ -0,0 +1,59 @@
+package indexer
+
+import (
+	"slices"
+	"sort"
+)
+
+// TagInfo 标签及带有该标签的文档
+type TagInfo struct {
+	Tag       string   `json:"tag"`
+	Count     int      `json:"count"`
+	Documents []string `json:"documents"`
+}
+
+// Tags 返回所有标签，按文档数从多到少排序，文档数相同时按标签名
+func (w *WikiIndexer) Tags() []TagInfo {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	docs := make(map[string][]string)
+	for relPath, doc := range w.docs {
+		for _, tag := range doc.tags {
+			docs[tag] = append(docs[tag], relPath)
+		}
+	}
+
+	tags := make([]TagInfo, 0, len(docs))
+	for tag, paths := range docs {
+		sort.Strings(paths)
+		tags = append(tags, TagInfo{Tag: tag, Count: len(paths), Documents: paths})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+// FileTags 返回文件的标签
+func (w *WikiIndexer) FileTags(relPath string) []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if doc, ok := w.docs[relPath]; ok {
+		return doc.tags
+	}
+	return nil
+}
+
+// hasTags 判断 have 是否包含 want 中的全部标签
+func hasTags(have, want []string) bool {
+	for _, tag := range want {
+		if !slices.Contains(have, tag) {
+			return false
+		}
+	}
+	return true
+}
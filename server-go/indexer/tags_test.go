@@ -0,0 +1,55 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "战斗"), 0755)
+	os.WriteFile(filepath.Join(dir, "战斗/冲击.md"), []byte("---\ntags: [PvP, \"需要程序确认\"]\n---\n冲击定义\n【韧性】：抵抗冲击的能力\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "战斗/滑移.md"), []byte("---\ntags:\n  - PvP\n  - PvP\n---\n滑移定义\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "背包.md"), []byte("背包定义\n"), 0644)
+
+	idx := New(dir)
+	idx.BuildIndex()
+
+	want := []TagInfo{
+		{Tag: "PvP", Count: 2, Documents: []string{"战斗/冲击.md", "战斗/滑移.md"}},
+		{Tag: "需要程序确认", Count: 1, Documents: []string{"战斗/冲击.md"}},
+	}
+	if got := idx.Tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags = %+v", got)
+	}
+	if got := idx.FileTags("战斗/滑移.md"); !reflect.DeepEqual(got, []string{"PvP"}) {
+		t.Errorf("FileTags = %v", got)
+	}
+
+	// 文件内定义继承文档的标签
+	if got := idx.Search("韧性", "需要程序确认"); len(got) != 1 {
+		t.Errorf("Search(韧性, 需要程序确认) = %d 个", len(got))
+	}
+	if got := idx.Search("韧性", "PvE"); len(got) != 0 {
+		t.Errorf("Search(韧性, PvE) = %d 个", len(got))
+	}
+	files := make(map[string]bool)
+	for _, term := range idx.Search("", "PvP") {
+		files[term.FilePath] = true
+	}
+	if len(files) != 2 || files["背包.md"] {
+		t.Errorf("Search(\"\", PvP) 的文件 = %v", files)
+	}
+	if idx.Search("") != nil {
+		t.Error("没有关键词和标签时应返回空")
+	}
+
+	// 修改文件后标签随之更新
+	os.WriteFile(filepath.Join(dir, "战斗/滑移.md"), []byte("滑移定义\n"), 0644)
+	idx.UpdateFile("战斗/滑移.md")
+	if got := idx.Tags(); len(got) != 2 || got[0].Count != 1 {
+		t.Errorf("更新后 Tags = %+v", got)
+	}
+}
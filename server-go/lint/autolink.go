@@ -0,0 +1,77 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"xlxz-wiki/indexer"
+)
+
+// Mention 正文中未加 【】 的词条或别名，供前端自动链接
+type Mention struct {
+	// Line、Column 所在的行和列（按字符计，从 1 开始）
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Text   string `json:"text"`
+	Term   string `json:"term"`
+}
+
+// linkTextRe 链接文本（地址已被 scanLines 替换为空格），前端不在链接中再嵌套词条
+var linkTextRe = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+
+// Mentions 按最长匹配查找文档中未加括号的词条和别名，遵循文档的 scope，
+// 跳过 frontmatter、标题、代码、公式、链接、图片、HTML 标签和文档自己定义的词条；禁用的说法不在其中
+func (c *Checker) Mentions(relPath, text string) []Mention {
+	vocab := c.vocabulary(indexer.ParseScope(text))
+	mentions := []Mention{}
+	for _, l := range scanLines(text) {
+		if l.heading {
+			continue
+		}
+		l.text = blankGroups(l.text, linkTextRe)
+		for _, m := range vocab.scan(relPath, l) {
+			if m.phrase.rule != RuleUnbracketed {
+				continue
+			}
+			mentions = append(mentions, Mention{
+				Line:   l.num,
+				Column: column(l.text, m.offset),
+				Text:   m.phrase.text,
+				Term:   m.phrase.term,
+			})
+		}
+	}
+	return mentions
+}
+
+// Change 批量加括号对一个文档的修改
+type Change struct {
+	File string
+	// Count 加括号的处数
+	Count    int
+	Old, New string
+}
+
+// Bracketize 为已索引文档中未加括号的词条和别名加上 【】，返回有修改的文档（按路径排序），不写入文件
+func Bracketize(idx *indexer.WikiIndexer, docsDir string) []Change {
+	c := NewChecker(idx.GetIndex())
+	var changes []Change
+	for _, relPath := range idx.Files() {
+		content, err := os.ReadFile(filepath.Join(docsDir, relPath))
+		if err != nil {
+			continue
+		}
+		var issues []Issue
+		for _, issue := range c.Check(relPath, string(content)) {
+			if issue.Rule == RuleUnbracketed {
+				issues = append(issues, issue)
+			}
+		}
+		fixed, n := Fix(string(content), issues)
+		if n > 0 {
+			changes = append(changes, Change{File: relPath, Count: n, Old: string(content), New: fixed})
+		}
+	}
+	return changes
+}
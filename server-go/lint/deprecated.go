@@ -0,0 +1,66 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"xlxz-wiki/indexer"
+)
+
+// termRefRe 词条引用 【词条】，与前端相同；后面紧跟冒号的是文件内定义，需另行排除
+var termRefRe = regexp.MustCompile(`【([^】{}]+)】`)
+
+// checkDeprecated 引用的词条已废弃：同名的定义都已废弃时才报告，有替代词条时给出替换建议
+func checkDeprecated(index *indexer.WikiIndex, relPath string, l line) []Issue {
+	var issues []Issue
+	for _, m := range termRefRe.FindAllStringSubmatchIndex(l.text, -1) {
+		if rest := l.text[m[1]:]; strings.HasPrefix(rest, "：") || strings.HasPrefix(rest, ":") {
+			continue
+		}
+		ref := l.text[m[2]:m[3]]
+		prefix, name := splitScope(ref)
+		def := deprecatedTerm(index.Terms[name])
+		if def == nil {
+			continue
+		}
+
+		issue := Issue{
+			File:    relPath,
+			Line:    l.num,
+			Column:  column(l.text, m[0]),
+			Rule:    RuleDeprecated,
+			Message: "【" + name + "】已废弃",
+			Text:    l.text[m[0]:m[1]],
+		}
+		if def.ReplacedBy != "" {
+			issue.Message += "，请改用【" + def.ReplacedBy + "】"
+			issue.Replacement = "【" + prefix + def.ReplacedBy + "】"
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// splitScope 拆出 scope/ 前缀；章节标题中的 / 不算
+func splitScope(ref string) (prefix, name string) {
+	head := ref
+	if i := strings.Index(ref, "#"); i > 0 {
+		head = ref[:i]
+	}
+	if i := strings.Index(head, "/"); i > 0 {
+		return ref[:i+1], ref[i+1:]
+	}
+	return "", ref
+}
+
+// deprecatedTerm 同名定义都已废弃时返回其中的文件定义，否则返回 nil
+func deprecatedTerm(defs []*indexer.WikiTerm) *indexer.WikiTerm {
+	var found *indexer.WikiTerm
+	for _, def := range defs {
+		if !def.Deprecated {
+			return nil
+		}
+		found = def
+	}
+	return found
+}
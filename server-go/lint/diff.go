@@ -0,0 +1,65 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext 差异前后保留的上下文行数
+const diffContext = 3
+
+// Diff 以 unified diff 格式输出修改，可以用 git apply 或 patch 应用；
+// 加括号只修改行内内容，新旧文本逐行对应
+func (c Change) Diff() string {
+	oldLines := strings.Split(c.Old, "\n")
+	newLines := strings.Split(c.New, "\n")
+	if len(oldLines) != len(newLines) {
+		return ""
+	}
+	// 文件以换行结尾时最后是一个空串，不算一行
+	n := len(oldLines)
+	if n > 0 && oldLines[n-1] == "" {
+		n--
+	}
+
+	var changed []int
+	for i := 0; i < n; i++ {
+		if oldLines[i] != newLines[i] {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", c.File, c.File)
+	for i := 0; i < len(changed); {
+		// 相邻修改的上下文重叠时合并为一块
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*diffContext {
+			j++
+		}
+		start := max(changed[i]-diffContext, 0)
+		end := min(changed[j]+diffContext+1, n)
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+		for k := start; k < end; k++ {
+			if oldLines[k] == newLines[k] {
+				writeDiffLine(&b, " ", oldLines[k], k == len(oldLines)-1)
+			} else {
+				writeDiffLine(&b, "-", oldLines[k], k == len(oldLines)-1)
+				writeDiffLine(&b, "+", newLines[k], k == len(newLines)-1)
+			}
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// writeDiffLine 写入一行差异，文件末尾没有换行时按 diff 的约定标注
+func writeDiffLine(b *strings.Builder, prefix, text string, last bool) {
+	b.WriteString(prefix + text + "\n")
+	if last {
+		b.WriteString("\\ No newline at end of file\n")
+	}
+}
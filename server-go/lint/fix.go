@@ -0,0 +1,59 @@
+package lint
+
+import (
+	"sort"
+	"strings"
+)
+
+// Fix 按检查结果中的替换建议修改文档，返回修改后的文本和实际修改的处数；
+// 原文已变化（Text 与 Line、Column 处的内容不符）或与其他修改重叠的问题跳过
+func Fix(text string, issues []Issue) (string, int) {
+	byLine := make(map[int][]Issue)
+	for _, issue := range issues {
+		if issue.Replacement != "" && issue.Text != "" {
+			byLine[issue.Line] = append(byLine[issue.Line], issue)
+		}
+	}
+	if len(byLine) == 0 {
+		return text, 0
+	}
+
+	lines := strings.Split(text, "\n")
+	fixed := 0
+	for num, list := range byLine {
+		if num < 1 || num > len(lines) {
+			continue
+		}
+		// 从右往左替换，前面问题的列号不受影响
+		sort.Slice(list, func(a, b int) bool { return list[a].Column > list[b].Column })
+		s := lines[num-1]
+		limit := len(s)
+		for _, issue := range list {
+			offset := byteOffset(s, issue.Column)
+			end := offset + len(issue.Text)
+			if offset < 0 || end > limit || s[offset:end] != issue.Text {
+				continue
+			}
+			s = s[:offset] + issue.Replacement + s[end:]
+			limit = offset
+			fixed++
+		}
+		lines[num-1] = s
+	}
+	return strings.Join(lines, "\n"), fixed
+}
+
+// byteOffset 列号（从 1 开始）对应的字节偏移，超出行尾时返回 -1
+func byteOffset(s string, col int) int {
+	n := 1
+	for i := range s {
+		if n == col {
+			return i
+		}
+		n++
+	}
+	if n == col {
+		return len(s)
+	}
+	return -1
+}
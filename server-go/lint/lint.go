@@ -0,0 +1,252 @@
+// Package lint 检查文档中不规范的写法：引用了已废弃的词条、使用了禁用的同义说法、
+// 提到词条却没有加 【】，供命令行和接口使用
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"xlxz-wiki/indexer"
+	"xlxz-wiki/markdown"
+)
+
+// 检查规则
+const (
+	// RuleDeprecated 引用了已废弃的词条
+	RuleDeprecated = "deprecated"
+	// RuleForbidden 使用了词条禁用的同义说法（synonyms-forbidden）或已废弃词条的旧说法
+	RuleForbidden = "forbidden-synonym"
+	// RuleUnbracketed 正文中提到了词条或别名，但没有写成 【词条】
+	RuleUnbracketed = "unbracketed"
+)
+
+// Issue 一条检查结果
+type Issue struct {
+	File string `json:"file"`
+	// Line、Column 问题所在的行和列（按字符计，从 1 开始）
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Text 命中的原文，Replacement 建议替换成的文本，没有建议时为空
+	Text        string `json:"text"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s [%s]", i.File, i.Line, i.Column, i.Message, i.Rule)
+}
+
+// Run 检查已索引的全部文档，结果按文件、行、列排序
+func Run(idx *indexer.WikiIndexer, docsDir string) []Issue {
+	c := NewChecker(idx.GetIndex())
+	issues := []Issue{}
+	for _, relPath := range idx.Files() {
+		content, err := os.ReadFile(filepath.Join(docsDir, relPath))
+		if err != nil {
+			continue
+		}
+		issues = append(issues, c.Check(relPath, string(content))...)
+	}
+	sortIssues(issues)
+	return issues
+}
+
+// Checker 按索引检查文档，各 scope 的词表只构建一次
+type Checker struct {
+	index  *indexer.WikiIndex
+	vocabs map[string]*vocabulary
+}
+
+// NewChecker 创建检查器，index 在检查期间不应被修改
+func NewChecker(index *indexer.WikiIndex) *Checker {
+	return &Checker{index: index, vocabs: make(map[string]*vocabulary)}
+}
+
+// Check 检查单个文档，结果按行、列排序
+func (c *Checker) Check(relPath, text string) []Issue {
+	vocab := c.vocabulary(indexer.ParseScope(text))
+
+	issues := []Issue{}
+	for _, l := range scanLines(text) {
+		issues = append(issues, checkDeprecated(c.index, relPath, l)...)
+		if !l.heading {
+			issues = append(issues, vocab.check(relPath, l)...)
+		}
+	}
+	sortIssues(issues)
+	return issues
+}
+
+// vocabulary 返回 scope 下的词表，首次使用时构建
+func (c *Checker) vocabulary(scope string) *vocabulary {
+	vocab, ok := c.vocabs[scope]
+	if !ok {
+		vocab = newVocabulary(c.index, scope)
+		c.vocabs[scope] = vocab
+	}
+	return vocab
+}
+
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(a, b int) bool {
+		if issues[a].File != issues[b].File {
+			return issues[a].File < issues[b].File
+		}
+		if issues[a].Line != issues[b].Line {
+			return issues[a].Line < issues[b].Line
+		}
+		return issues[a].Column < issues[b].Column
+	})
+}
+
+// Write 按 format（text / json）输出检查结果
+func Write(w io.Writer, issues []Issue, format string) error {
+	switch format {
+	case "text", "":
+		for _, issue := range issues {
+			if _, err := fmt.Fprintln(w, issue); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(issues)
+	}
+	return fmt.Errorf("未知格式: %s", format)
+}
+
+var (
+	// refDefRe 链接引用定义 [标签]: 地址，整行不检查
+	refDefRe = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:`)
+	// syntaxRe 不属于正文的语法：图片、自动链接、HTML 标签和注释、裸链接，整段不检查
+	syntaxRe = regexp.MustCompile(`!\[[^\]]*\]\((?:<[^>]*>|[^)]*)\)` +
+		`|<[a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*>|<[^\s@<>]+@[^\s@<>]+>` +
+		`|</?[a-zA-Z][^<>]*>|<!--.*?-->` +
+		`|(?:https?|ftp)://[^\s<>]*|www\.[^\s<>]*`)
+	// linkTargetRe 链接地址 [文本](地址) 和引用链接的标签 [文本][标签]：只跳过第 1 组，链接文本照常检查
+	linkTargetRe = regexp.MustCompile(`\]\((<[^>]*>|[^)]*)\)|\]\[([^\]]*)\]`)
+)
+
+// line 需要检查的一行：跳过 frontmatter、代码块、HTML 块和链接引用定义，
+// 行内代码、链接地址等不属于正文的部分替换为等长（按字符计）的空格以保持列号
+type line struct {
+	num     int
+	text    string
+	heading bool // ATX 标题，不检查未加括号的词条
+}
+
+func scanLines(text string) []line {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	start := 0
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	var result []line
+	code := markdown.CodeLines(text)
+	for i := start; i < len(lines); i++ {
+		if code[i+1] {
+			continue
+		}
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if refDefRe.MatchString(lines[i]) {
+			continue
+		}
+		text := blankCodeSpans(lines[i])
+		text = syntaxRe.ReplaceAllStringFunc(text, blank)
+		text = blankGroups(text, linkTargetRe)
+		result = append(result, line{num: i + 1, text: text, heading: strings.HasPrefix(trimmed, "#")})
+	}
+	return result
+}
+
+// blankCodeSpans 将行内代码替换为空格：开头的反引号串与之后第一个等长的反引号串配对，
+// 找不到配对的反引号串按普通文本处理
+func blankCodeSpans(s string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		n := backtickRun(s, i)
+		if i > 0 && s[i-1] == '\\' {
+			i += n
+			continue
+		}
+		end := -1
+		for j := i + n; j < len(s); {
+			if s[j] != '`' {
+				j++
+				continue
+			}
+			m := backtickRun(s, j)
+			if m == n {
+				end = j + m
+				break
+			}
+			j += m
+		}
+		if end < 0 {
+			i += n
+			continue
+		}
+		b.WriteString(s[last:i])
+		b.WriteString(blank(s[i:end]))
+		last, i = end, end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func backtickRun(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == '`' {
+		n++
+	}
+	return n
+}
+
+// blank 替换为等长（按字符计）的空格
+func blank(s string) string {
+	return strings.Repeat(" ", utf8.RuneCountInString(s))
+}
+
+// blankGroups 将 re 各匹配中的子匹配替换为空格，匹配的其余部分保留
+func blankGroups(s string, re *regexp.Regexp) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		for g := 2; g < len(m); g += 2 {
+			if m[g] < 0 {
+				continue
+			}
+			b.WriteString(s[last:m[g]])
+			b.WriteString(blank(s[m[g]:m[g+1]]))
+			last = m[g+1]
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// column 字节偏移对应的列号
+func column(s string, offset int) int {
+	return utf8.RuneCountInString(s[:offset]) + 1
+}
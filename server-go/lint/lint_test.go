@@ -0,0 +1,223 @@
+package lint
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"xlxz-wiki/indexer"
+)
+
+func TestRun_Deprecated(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"删除建筑.md": "---\ndeprecated: true\nreplacedBy: 【拆除建筑】\n---\n",
+		"拆除建筑.md": "移除建筑并返还资源\n",
+		"移除建筑.md": "---\ndeprecated: true\n---\n旧说法\n",
+		"建造.md": "---\nscope: 城建\n---\n" +
+			"玩家可以【删除建筑】，也可以【城建/删除建筑】。\n" +
+			"`【删除建筑】` 在行内代码中\n" +
+			"```\n【删除建筑】\n```\n" +
+			"【移除建筑】【拆除建筑】\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	idx := indexer.New(dir)
+	idx.BuildIndex()
+
+	issues := Run(idx, dir)
+	want := []Issue{
+		{File: "建造.md", Line: 4, Column: 5, Rule: RuleDeprecated, Message: "【删除建筑】已废弃，请改用【拆除建筑】", Text: "【删除建筑】", Replacement: "【拆除建筑】"},
+		{File: "建造.md", Line: 4, Column: 15, Rule: RuleDeprecated, Message: "【删除建筑】已废弃，请改用【拆除建筑】", Text: "【城建/删除建筑】", Replacement: "【城建/拆除建筑】"},
+		{File: "建造.md", Line: 9, Column: 1, Rule: RuleDeprecated, Message: "【移除建筑】已废弃", Text: "【移除建筑】"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %+v", issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issues[%d] = %+v\nwant %+v", i, issues[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, issues[2:], "text"); err != nil || buf.String() != "建造.md:9:1: 【移除建筑】已废弃 [deprecated]\n" {
+		t.Errorf("Write = %q, %v", buf.String(), err)
+	}
+	if err := Write(&buf, issues, "xml"); err == nil {
+		t.Error("未知格式应返回错误")
+	}
+}
+
+func TestChecker_Vocabulary(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"拆除建筑.md":  "---\naliases: [拆除]\nsynonyms-forbidden: [删除, 摧毁, 移除]\n---\n拆除后返还资源，不要写作摧毁\n",
+		"建筑.md":    "可以建造的设施\n",
+		"HP.md":    "生命值\n",
+		"城建/工地.md": "---\nscope: 城建\n---\n施工中的建筑\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	idx := indexer.New(dir)
+	idx.BuildIndex()
+
+	text := "# 拆除建筑\n" +
+		"玩家可以删除建筑，也可以【拆除建筑】。\n" +
+		"`拆除建筑` 和 %%HP%% 不检查，HPMAX 不是 HP。\n" +
+		"工地里的建筑\n"
+	issues := NewChecker(idx.GetIndex()).Check("玩法.md", text)
+	want := []Issue{
+		{File: "玩法.md", Line: 2, Column: 5, Rule: RuleForbidden, Message: "「删除」是禁用的说法，请改用【拆除建筑】", Text: "删除", Replacement: "【拆除建筑】"},
+		{File: "玩法.md", Line: 2, Column: 7, Rule: RuleUnbracketed, Message: "「建筑」是词条，应写作【建筑】", Text: "建筑", Replacement: "【建筑】"},
+		{File: "玩法.md", Line: 3, Column: 30, Rule: RuleUnbracketed, Message: "「HP」是词条，应写作【HP】", Text: "HP", Replacement: "【HP】"},
+		{File: "玩法.md", Line: 4, Column: 5, Rule: RuleUnbracketed, Message: "「建筑」是词条，应写作【建筑】", Text: "建筑", Replacement: "【建筑】"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %+v", issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issues[%d] = %+v\nwant %+v", i, issues[i], want[i])
+		}
+	}
+
+	// 定义所在的文件中提到自己的别名和禁用说法不算问题；scope 内的词条只在同 scope 的文档中检查
+	own := NewChecker(idx.GetIndex()).Check("拆除建筑.md", files["拆除建筑.md"])
+	if len(own) != 0 {
+		t.Errorf("own = %+v", own)
+	}
+	scoped := NewChecker(idx.GetIndex()).Check("城建/施工.md", "---\nscope: 城建\n---\n进入工地\n")
+	if len(scoped) != 1 || scoped[0].Text != "工地" || scoped[0].Line != 4 {
+		t.Errorf("scoped = %+v", scoped)
+	}
+
+	fixed, n := Fix(text, issues)
+	if n != 4 {
+		t.Errorf("Fix n = %d", n)
+	}
+	if want := "# 拆除建筑\n" +
+		"玩家可以【拆除建筑】【建筑】，也可以【拆除建筑】。\n" +
+		"`拆除建筑` 和 %%HP%% 不检查，HPMAX 不是 【HP】。\n" +
+		"工地里的【建筑】\n"; fixed != want {
+		t.Errorf("Fix = %q", fixed)
+	}
+	// 原文已变化的问题跳过
+	if _, n := Fix(fixed, issues); n != 0 {
+		t.Errorf("再次 Fix n = %d", n)
+	}
+}
+
+func TestChecker_Mentions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"拆除建筑.md":  "---\nsynonyms-forbidden: [删除]\n---\n移除建筑并返还资源\n",
+		"建筑.md":    "可以建造的设施\n",
+		"城建/工地.md": "---\nscope: 城建\n---\n施工中的建筑\n",
+		"玩法.md":    "# 建筑\n删除建筑前先拆除建筑，【建筑】已链接。\n\n```\n建筑\n```\n",
+		"城建/进场.md": "---\nscope: 城建\n---\n进入工地\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	idx := indexer.New(dir)
+	idx.BuildIndex()
+
+	c := NewChecker(idx.GetIndex())
+	mentions := c.Mentions("玩法.md", files["玩法.md"])
+	want := []Mention{
+		{Line: 2, Column: 3, Text: "建筑", Term: "建筑"},
+		{Line: 2, Column: 7, Text: "拆除建筑", Term: "拆除建筑"},
+	}
+	if len(mentions) != len(want) || mentions[0] != want[0] || mentions[1] != want[1] {
+		t.Errorf("mentions = %+v", mentions)
+	}
+	if m := c.Mentions("城建/进场.md", files["城建/进场.md"]); len(m) != 1 || m[0].Term != "工地" {
+		t.Errorf("scope 内 mentions = %+v", m)
+	}
+
+	changes := Bracketize(idx, dir)
+	var names []string
+	for _, change := range changes {
+		names = append(names, change.File)
+	}
+	if strings.Join(names, ",") != "城建/工地.md,城建/进场.md,拆除建筑.md,玩法.md" || changes[3].Count != 2 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	wantDiff := "--- a/玩法.md\n+++ b/玩法.md\n@@ -1,5 +1,5 @@\n # 建筑\n" +
+		"-删除建筑前先拆除建筑，【建筑】已链接。\n" +
+		"+删除【建筑】前先【拆除建筑】，【建筑】已链接。\n" +
+		" \n ```\n 建筑\n"
+	if diff := changes[3].Diff(); diff != wantDiff {
+		t.Errorf("Diff = %q", diff)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "玩法.md")); string(content) != files["玩法.md"] {
+		t.Error("Bracketize 不应写入文件")
+	}
+}
+
+func TestFix_LinksUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "冲击.md"), []byte("受击后的硬直\n"), 0644)
+	idx := indexer.New(dir)
+	idx.BuildIndex()
+
+	text := "[说明](冲击.md) <img src=\"冲击.png\"> ![冲击图](img/冲击.png) <http://x/冲击>\n" +
+		"[说明][冲击] <!-- 冲击 --> https://x/冲击 <a href=\"冲击.md\">说明</a>\n" +
+		"[冲击]: 冲击.md \"冲击\"\n"
+	if issues := NewChecker(idx.GetIndex()).Check("玩法.md", text); len(issues) != 0 {
+		t.Fatalf("链接地址、图片和 HTML 中不应检查: %+v", issues)
+	}
+
+	text = "[冲击说明](<冲击 1.md> \"冲击\")冲击\n"
+	issues := NewChecker(idx.GetIndex()).Check("玩法.md", text)
+	fixed, n := Fix(text, issues)
+	if n != 2 || fixed != "[【冲击】说明](<冲击 1.md> \"冲击\")【冲击】\n" {
+		t.Errorf("Fix = %q, %d", fixed, n)
+	}
+}
+
+func TestMentions_Links(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "冲击.md"), []byte("受击后的硬直\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "玩法.md"), []byte("[冲击说明](冲击.md) <http://x/冲击> 冲击\n"), 0644)
+	idx := indexer.New(dir)
+	idx.BuildIndex()
+
+	text := "[冲击说明](冲击.md) <img src=\"冲击.png\"> https://x/冲击\n后续冲击\n"
+	mentions := NewChecker(idx.GetIndex()).Mentions("玩法.md", text)
+	if len(mentions) != 1 || mentions[0] != (Mention{Line: 2, Column: 3, Text: "冲击", Term: "冲击"}) {
+		t.Errorf("mentions = %+v", mentions)
+	}
+
+	changes := Bracketize(idx, dir)
+	if len(changes) != 1 || changes[0].New != "[【冲击】说明](冲击.md) <http://x/冲击> 【冲击】\n" {
+		t.Errorf("changes = %+v", changes)
+	}
+}
+
+func TestFix_CodeUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "冲击.md"), []byte("受击后的硬直\n"), 0644)
+	idx := indexer.New(dir)
+	idx.BuildIndex()
+
+	text := "````\n```\n冲击\n```\n````\n" +
+		"~~~\n冲击\n```\n冲击\n~~~\n\n" +
+		"    冲击 = 1\n\n" +
+		"<div>\n冲击\n</div>\n\n" +
+		"- 列表\n\n      冲击 = 2\n\n" +
+		"``a ` 冲击`` 冲击\n"
+	issues := NewChecker(idx.GetIndex()).Check("玩法.md", text)
+	fixed, n := Fix(text, issues)
+	want := strings.Replace(text, "`` 冲击\n", "`` 【冲击】\n", 1)
+	if n != 1 || fixed != want {
+		t.Errorf("代码块、HTML 块和行内代码不应修改: Fix = %q, %d", fixed, n)
+	}
+}
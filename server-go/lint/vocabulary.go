@@ -0,0 +1,156 @@
+package lint
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"xlxz-wiki/indexer"
+)
+
+// maskRe 不检查的部分：已有的 【词条】 和 %% 公式 %%
+var maskRe = regexp.MustCompile(`【[^】]*】|%%.*?%%`)
+
+// phrase 正文中不应直接出现的说法
+type phrase struct {
+	text string
+	rule string
+	// term 应改用的词条
+	term string
+	// files 定义该词条的文件，这些文件中提到自己不算问题
+	files []string
+}
+
+// vocabulary 一个 scope 下可见的词条别名和禁用说法，按首字分组、每组从长到短排列，用于最长匹配
+type vocabulary struct {
+	byFirst map[rune][]*phrase
+}
+
+// minAliasLen 短于此字数的别名不检查是否加了括号，避免误报
+const minAliasLen = 2
+
+// newVocabulary 收集全局和 scope 内的文件、章节词条：别名需要加括号，禁用说法和废弃词条的旧说法需要替换
+func newVocabulary(index *indexer.WikiIndex, scope string) *vocabulary {
+	phrases := make(map[string]*phrase)
+	add := func(text, rule, term, file string) {
+		p := phrases[text]
+		if p == nil || p.rule == RuleUnbracketed && rule == RuleForbidden {
+			p = &phrase{text: text, rule: rule, term: term}
+			phrases[text] = p
+		}
+		if !slices.Contains(p.files, file) {
+			p.files = append(p.files, file)
+		}
+	}
+
+	for alias, defs := range index.Terms {
+		for _, def := range defs {
+			if def.Scope != "" && def.Scope != scope || def.DefinitionType == "inline" {
+				continue
+			}
+			for _, s := range def.ForbiddenSynonyms {
+				add(s, RuleForbidden, def.Term, def.FilePath)
+			}
+			switch {
+			case def.Deprecated && def.ReplacedBy != "":
+				add(alias, RuleForbidden, def.ReplacedBy, def.FilePath)
+			case def.Deprecated || strings.Contains(alias, "#") || utf8.RuneCountInString(alias) < minAliasLen:
+			default:
+				add(alias, RuleUnbracketed, alias, def.FilePath)
+			}
+		}
+	}
+
+	v := &vocabulary{byFirst: make(map[rune][]*phrase)}
+	for _, p := range phrases {
+		r, _ := utf8.DecodeRuneInString(p.text)
+		v.byFirst[r] = append(v.byFirst[r], p)
+	}
+	for _, list := range v.byFirst {
+		sort.Slice(list, func(i, j int) bool {
+			if len(list[i].text) != len(list[j].text) {
+				return len(list[i].text) > len(list[j].text)
+			}
+			return list[i].text < list[j].text
+		})
+	}
+	return v
+}
+
+// match 正文中的一处说法，offset 为字节偏移
+type match struct {
+	offset int
+	phrase *phrase
+}
+
+// check 从左到右按最长匹配查找未加括号的说法
+func (v *vocabulary) check(relPath string, l line) []Issue {
+	var issues []Issue
+	for _, m := range v.scan(relPath, l) {
+		issues = append(issues, m.phrase.issue(relPath, l, m.offset))
+	}
+	return issues
+}
+
+// scan 按最长匹配查找说法，跳过已有的 【】、公式和 relPath 自己定义的词条
+func (v *vocabulary) scan(relPath string, l line) []match {
+	masked := maskRe.ReplaceAllStringFunc(l.text, func(s string) string {
+		return strings.Repeat("\x00", len(s))
+	})
+
+	var matches []match
+	for i := 0; i < len(masked); {
+		r, size := utf8.DecodeRuneInString(masked[i:])
+		p := v.match(masked, i, v.byFirst[r])
+		if p == nil {
+			i += size
+			continue
+		}
+		if !slices.Contains(p.files, relPath) {
+			matches = append(matches, match{offset: i, phrase: p})
+		}
+		i += len(p.text)
+	}
+	return matches
+}
+
+// match 返回从 i 开始的最长说法；英文、数字开头或结尾的说法须在单词边界上
+func (v *vocabulary) match(s string, i int, candidates []*phrase) *phrase {
+	for _, p := range candidates {
+		if !strings.HasPrefix(s[i:], p.text) {
+			continue
+		}
+		if isWordByte(p.text[0]) && i > 0 && isWordByte(s[i-1]) {
+			continue
+		}
+		end := i + len(p.text)
+		if isWordByte(p.text[len(p.text)-1]) && end < len(s) && isWordByte(s[end]) {
+			continue
+		}
+		return p
+	}
+	return nil
+}
+
+func (p *phrase) issue(relPath string, l line, offset int) Issue {
+	issue := Issue{
+		File:        relPath,
+		Line:        l.num,
+		Column:      column(l.text, offset),
+		Rule:        p.rule,
+		Text:        p.text,
+		Replacement: "【" + p.term + "】",
+	}
+	if p.rule == RuleForbidden {
+		issue.Message = "「" + p.text + "」是禁用的说法，请改用【" + p.term + "】"
+	} else {
+		issue.Message = "「" + p.text + "」是词条，应写作【" + p.term + "】"
+	}
+	return issue
+}
+
+func isWordByte(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b == '_'
+}
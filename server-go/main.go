@@ -3,22 +3,21 @@ package main
 import (
 	"context"
 	"embed"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io/fs"
 	"log"
 	"net"
-	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
-	"strings"
+	"syscall"
 
-	"xlxz-wiki/indexer"
-	"xlxz-wiki/watcher"
-	"xlxz-wiki/ws"
+	"xlxz-wiki/auth"
+	"xlxz-wiki/config"
+	"xlxz-wiki/server"
 )
 
 //go:embed dist/* dist/assets/*
@@ -27,406 +26,171 @@ var distFS embed.FS
 // 版本号，通过 -ldflags 在编译时注入
 var Version = "dev"
 
-var (
-	wikiDocsDir string
-	idx         *indexer.WikiIndexer
-	hub         *ws.Hub
-)
-
 func main() {
-	// 解析命令行参数
-	docsFlag := flag.String("docs", "", "wiki 文档目录路径")
-	flag.Parse()
-
-	// 解析路径
-	rootDir, _ := os.Getwd()
-	if *docsFlag != "" {
-		// 支持相对路径和绝对路径
-		if filepath.IsAbs(*docsFlag) {
-			wikiDocsDir = *docsFlag
-		} else {
-			wikiDocsDir = filepath.Join(rootDir, *docsFlag)
+	// 子命令
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
 		}
-	} else {
-		wikiDocsDir = filepath.Join(rootDir, "wiki-docs")
 	}
-	distDir := filepath.Join(rootDir, "dist")
 
-	// 初始化 WebSocket Hub
-	hub = ws.NewHub()
-	go hub.Run()
-
-	// 初始化索引器
-	idx = indexer.New(wikiDocsDir)
-	if err := idx.BuildIndex(); err != nil {
-		log.Printf("[索引] 构建失败: %v", err)
+	// 加载配置：默认值 < 配置文件 < 环境变量 < 命令行参数
+	loadConfig := configFlags(flag.CommandLine)
+	flag.Parse()
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("[配置] %v", err)
+	}
+	if cfg.Path != "" {
+		log.Printf("[配置] 使用 %s", cfg.Path)
 	}
 
-	// 启动文件监听
-	go watcher.Watch(wikiDocsDir, idx, hub)
-
-	// 路由
-	http.HandleFunc("/api/index", handleIndex)
-	http.HandleFunc("/api/file", handleFile)
-	http.HandleFunc("/api/files", handleFiles)
-	http.HandleFunc("/api/search", handleSearch)
-	http.HandleFunc("/api/version", handleVersion)
-	http.HandleFunc("/api/debug/index", handleDebugIndex)
-	http.HandleFunc("/api/annotations", handleAnnotations)
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		ws.ServeWs(hub, w, r)
-	})
-
-	// 静态文件服务
-	if hasEmbeddedDist() {
-		// 编译模式：从内嵌资源读取
-		distSub, _ := fs.Sub(distFS, "dist")
-		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			path := r.URL.Path
-			// 尝试读取静态文件
-			if path != "/" {
-				filePath := strings.TrimPrefix(path, "/")
-				if content, err := fs.ReadFile(distSub, filePath); err == nil {
-					// 设置正确的 MIME 类型
-					contentType := getMimeType(filePath)
-					w.Header().Set("Content-Type", contentType)
-					w.Write(content)
-					return
-				}
-			}
-			// SPA 回退：返回 index.html
-			indexContent, _ := fs.ReadFile(distSub, "index.html")
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.Write(indexContent)
-		})
-	} else if dirExists(distDir) {
-		// 开发模式：从文件系统读取
-		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			path := r.URL.Path
-			// 尝试读取静态文件
-			if path != "/" {
-				filePath := filepath.Join(distDir, path)
-				if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
-					http.ServeFile(w, r, filePath)
-					return
-				}
-			}
-			// SPA 回退：返回 index.html
-			http.ServeFile(w, r, filepath.Join(distDir, "index.html"))
-		})
-	} else {
-		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(404)
-			w.Write([]byte("XLXZ Wiki — 前端资源未构建，请先运行 pnpm build"))
-		})
+	srv, err := server.New(server.Config{Config: *cfg, Assets: assets(), Version: Version})
+	if err != nil {
+		log.Fatalf("[配置] %v", err)
 	}
 
 	// 查找可用端口
-	port := findAvailablePort(3055, 10)
+	ln, port, err := listenAvailablePort(cfg.Host, cfg.Port, cfg.PortSearch)
+	if err != nil {
+		log.Fatalf("[端口] %v", err)
+	}
+	scheme := "http"
+	if cfg.TLS.Enabled() {
+		scheme = "https"
+		if cfg.TLS.SelfSigned {
+			if err := ensureSelfSignedCert(cfg.TLS.Cert, cfg.TLS.Key, cfg.Host); err != nil {
+				log.Fatalf("[TLS] 生成自签名证书失败: %v", err)
+			}
+		}
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(displayHost(cfg.Host), fmt.Sprint(port)), cfg.BasePath)
 
 	// 打印启动信息
+	workspaces := srv.Workspaces()
+	docs := workspaces[0].Docs
+	if len(workspaces) > 1 {
+		docs = ""
+		for i, w := range workspaces {
+			path := url
+			if i > 0 {
+				path += "w/" + w.Name + "/"
+			}
+			docs += fmt.Sprintf("\n║    %s  %s  %s", w.Name, path, w.Docs)
+		}
+	}
 	fmt.Printf(`
 ╔══════════════════════════════════════════╗
 ║         XLXZ Wiki v4 服务器已启动         ║
 ║              (Go Edition)                ║
 ╠══════════════════════════════════════════╣
 ║  版本: v%s
-║  地址: http://127.0.0.1:%d
+║  地址: %s
 ║  文档: %s
 ╚══════════════════════════════════════════╝
-`, Version, port, wikiDocsDir)
-
-	// Windows 下自动打开浏览器
-	if runtime.GOOS == "windows" {
-		exec.Command("cmd", "/c", "start", fmt.Sprintf("http://127.0.0.1:%d/", port)).Start()
+`, Version, url, docs)
+	if cfg.ReadOnly {
+		log.Printf("[配置] 只读模式：拒绝所有修改请求，文件变更仍会实时推送")
 	}
-
-	// 启动服务器
-	addr := fmt.Sprintf(":%d", port)
-	log.Fatal(http.ListenAndServe(addr, nil))
-}
-
-// API 处理函数
-func handleIndex(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(idx.GetIndex())
-}
-
-func handleFile(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Query().Get("path")
-	if path == "" {
-		http.Error(w, "缺少 path 参数", 400)
-		return
+	authenticator := srv.Authenticator()
+	if authenticator.Enabled() {
+		log.Printf("[访问控制] 已启用，未登录访问者角色: %s", authenticator.Anonymous())
+	} else if authenticator.Anonymous() < auth.Editor {
+		log.Printf("[访问控制] 未配置账号，访问者只能浏览；需要编辑时设置 auth.anonymous: editor")
+	} else if !cfg.Loopback() {
+		log.Printf("[访问控制] 警告：监听 %s 且未配置账号，网络上的任何人都可以修改文档", cfg.Host)
 	}
 
-	fullPath := filepath.Join(wikiDocsDir, path)
-	
-	// 安全检查：防止路径遍历
-	if !strings.HasPrefix(fullPath, wikiDocsDir) {
-		http.Error(w, "非法路径", 403)
-		return
+	if cfg.OpenBrowser {
+		openBrowser(url)
 	}
 
-	if r.Method == "POST" {
-		// 写入文件
-		var body struct {
-			Content string `json:"content"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			http.Error(w, "无效的请求体", 400)
-			return
-		}
-		if err := os.WriteFile(fullPath, []byte(body.Content), 0644); err != nil {
-			http.Error(w, "写入失败: "+err.Error(), 500)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]bool{"success": true})
-	} else {
-		// 读取文件
-		content, err := os.ReadFile(fullPath)
-		if err != nil {
-			http.Error(w, "文件不存在", 404)
-			return
-		}
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.Write(content)
+	// 启动服务器，Ctrl+C 或 SIGTERM 时优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := srv.Serve(ctx, ln); err != nil {
+		log.Fatalf("[服务] %v", err)
 	}
+	log.Printf("[服务] 已关闭")
 }
 
-func handleFiles(w http.ResponseWriter, r *http.Request) {
-	tree := buildFileTree(wikiDocsDir, "")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tree)
-}
-
-func handleSearch(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query().Get("q")
-	results := idx.Search(q)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
-}
-
-func handleVersion(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"version": Version})
-}
-
-func handleAnnotations(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Query().Get("path")
-	if path == "" {
-		http.Error(w, "缺少 path 参数", 400)
-		return
-	}
-
-	// 批注存储目录：wiki-docs/.annotations/
-	annotationsDir := filepath.Join(wikiDocsDir, ".annotations")
-
-	// 将文档路径转为批注文件名：a/b/c.md → a_b_c.json
-	annotationFileName := strings.ReplaceAll(path, "/", "_")
-	annotationFileName = strings.ReplaceAll(annotationFileName, "\\", "_")
-	if strings.HasSuffix(annotationFileName, ".md") {
-		annotationFileName = annotationFileName[:len(annotationFileName)-3]
-	}
-	annotationFileName += ".json"
-
-	annotationPath := filepath.Join(annotationsDir, annotationFileName)
-
-	// 安全检查
-	if !strings.HasPrefix(annotationPath, annotationsDir) {
-		http.Error(w, "非法路径", 403)
-		return
+// assets 前端资源：编译模式从内嵌资源读取，开发模式从 ./dist 读取
+func assets() fs.FS {
+	if _, err := distFS.ReadFile("dist/index.html"); err == nil {
+		sub, _ := fs.Sub(distFS, "dist")
+		return sub
 	}
-
-	if r.Method == "POST" {
-		// 确保目录存在
-		if err := os.MkdirAll(annotationsDir, 0755); err != nil {
-			http.Error(w, "创建目录失败: "+err.Error(), 500)
-			return
-		}
-
-		// 读取请求体并格式化写入
-		var body json.RawMessage
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			http.Error(w, "无效的请求体", 400)
-			return
-		}
-
-		// 格式化 JSON（便于人类阅读和 agent 解析）
-		var formatted []byte
-		formatted, err := json.MarshalIndent(json.RawMessage(body), "", "  ")
-		if err != nil {
-			formatted = body
-		}
-
-		if err := os.WriteFile(annotationPath, formatted, 0644); err != nil {
-			http.Error(w, "写入失败: "+err.Error(), 500)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]bool{"success": true})
-	} else {
-		// 读取批注文件
-		content, err := os.ReadFile(annotationPath)
-		if err != nil {
-			http.Error(w, "批注不存在", 404)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.Write(content)
+	rootDir, _ := os.Getwd()
+	distDir := filepath.Join(rootDir, "dist")
+	if info, err := os.Stat(distDir); err == nil && info.IsDir() {
+		return os.DirFS(distDir)
 	}
+	return nil
 }
 
-func handleDebugIndex(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	index := idx.GetIndex()
-
-	type debugInfo struct {
-		TermCount    int                 `json:"termCount"`
-		FormulaCount int                 `json:"formulaCount"`
-		Scopes       []string            `json:"scopes"`
-		BuildTime    int64               `json:"buildTime"`
-		Terms        map[string][]string `json:"terms"`
-	}
-
-	// 简化词条信息：alias → [filePath, ...]
-	termsSimple := make(map[string][]string)
-	for alias, defs := range index.Terms {
-		var files []string
-		for _, d := range defs {
-			files = append(files, fmt.Sprintf("%s (%s)", d.FilePath, d.DefinitionType))
-		}
-		termsSimple[alias] = files
-	}
-
-	info := debugInfo{
-		TermCount:    len(index.Terms),
-		FormulaCount: len(index.Formulas),
-		Scopes:       index.Scopes,
-		BuildTime:    index.BuildTime,
-		Terms:        termsSimple,
+// configFlags 注册与配置项对应的命令行参数，返回的函数按这些参数加载配置
+// 只有显式设置的参数才会覆盖配置文件和环境变量
+func configFlags(fs *flag.FlagSet) func() (*config.Config, error) {
+	path := fs.String("config", "", "配置文件路径，默认查找文档目录旁的 "+config.FileName)
+	fs.String("docs", "", "wiki 文档目录路径")
+	fs.String("host", "", "监听地址")
+	fs.Int("port", 0, "起始端口")
+	fs.Bool("readonly", false, "只读模式")
+	fs.Bool("open", false, "启动后自动打开浏览器")
+	keys := map[string]string{
+		"docs":     "docs",
+		"host":     "listen.host",
+		"port":     "listen.port",
+		"readonly": "readOnly",
+		"open":     "openBrowser",
+	}
+
+	return func() (*config.Config, error) {
+		flags := make(map[string]string)
+		fs.Visit(func(f *flag.Flag) {
+			if key, ok := keys[f.Name]; ok {
+				flags[key] = f.Value.String()
+			}
+		})
+		return config.Load(config.Options{Path: *path, Flags: flags})
 	}
-	json.NewEncoder(w).Encode(info)
 }
 
-// 辅助函数
-func hasEmbeddedDist() bool {
-	_, err := distFS.ReadFile("dist/index.html")
-	return err == nil
-}
-
-func dirExists(path string) bool {
-	info, err := os.Stat(path)
-	return err == nil && info.IsDir()
+// displayHost 启动信息和浏览器使用的地址，监听所有网卡时显示本机地址
+func displayHost(host string) string {
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		return "127.0.0.1"
+	}
+	return host
 }
 
-func getMimeType(filePath string) string {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	switch ext {
-	case ".html":
-		return "text/html; charset=utf-8"
-	case ".css":
-		return "text/css; charset=utf-8"
-	case ".js":
-		return "application/javascript; charset=utf-8"
-	case ".json":
-		return "application/json; charset=utf-8"
-	case ".png":
-		return "image/png"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".gif":
-		return "image/gif"
-	case ".svg":
-		return "image/svg+xml"
-	case ".ico":
-		return "image/x-icon"
-	case ".woff":
-		return "font/woff"
-	case ".woff2":
-		return "font/woff2"
-	case ".ttf":
-		return "font/ttf"
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
 	default:
-		return "application/octet-stream"
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("[浏览器] 打开失败: %v", err)
 	}
 }
 
-func findAvailablePort(start, maxAttempts int) int {
+// listenAvailablePort 从 start 开始依次尝试监听，直接返回监听器，避免探测后再监听之间端口被占用
+func listenAvailablePort(host string, start, maxAttempts int) (net.Listener, int, error) {
 	for i := 0; i < maxAttempts; i++ {
 		port := start + i
-		addr := fmt.Sprintf(":%d", port)
+		addr := net.JoinHostPort(host, fmt.Sprint(port))
 		listener, err := (&net.ListenConfig{}).Listen(context.Background(), "tcp", addr)
 		if err == nil {
-			listener.Close()
 			if port != start {
 				log.Printf("[端口] %d 已被占用，使用 %d", start, port)
 			}
-			return port
+			return listener, port, nil
 		}
 	}
-	log.Fatalf("无法找到可用端口 (尝试了 %d-%d)", start, start+maxAttempts-1)
-	return start
-}
-
-// FileTreeNode 文件树节点
-type FileTreeNode struct {
-	Name        string          `json:"name"`
-	Path        string          `json:"path"`
-	IsDirectory bool            `json:"isDirectory"`
-	Children    []*FileTreeNode `json:"children,omitempty"`
-	ReadmePath  string          `json:"readmePath,omitempty"`
-}
-
-func buildFileTree(rootDir, relativePath string) []*FileTreeNode {
-	fullPath := filepath.Join(rootDir, relativePath)
-	entries, err := os.ReadDir(fullPath)
-	if err != nil {
-		return []*FileTreeNode{}
-	}
-
-	nodes := make([]*FileTreeNode, 0)
-	for _, entry := range entries {
-		name := entry.Name()
-		if strings.HasPrefix(name, ".") {
-			continue
-		}
-
-		nodePath := filepath.Join(relativePath, name)
-		node := &FileTreeNode{
-			Name:        name,
-			Path:        filepath.ToSlash(nodePath),
-			IsDirectory: entry.IsDir(),
-		}
-
-		if entry.IsDir() {
-			node.Children = buildFileTree(rootDir, nodePath)
-		} else if !strings.HasSuffix(name, ".md") {
-			continue // 只显示 .md 文件
-		}
-
-		nodes = append(nodes, node)
-	}
-
-	// 合并同名目录和 .md 文件
-	dirMap := make(map[string]*FileTreeNode)
-	for _, node := range nodes {
-		if node.IsDirectory {
-			dirMap[node.Name] = node
-		}
-	}
-
-	filteredNodes := make([]*FileTreeNode, 0, len(nodes))
-	for _, node := range nodes {
-		if !node.IsDirectory && strings.HasSuffix(node.Name, ".md") {
-			dirName := strings.TrimSuffix(node.Name, ".md")
-			if dirNode, exists := dirMap[dirName]; exists {
-				dirNode.ReadmePath = node.Path
-				continue // 跳过该 .md 文件节点，不加入最终列表
-			}
-		}
-		filteredNodes = append(filteredNodes, node)
-	}
-
-	return filteredNodes
+	return nil, 0, fmt.Errorf("无法找到可用端口 (尝试了 %d-%d)", start, start+maxAttempts-1)
 }
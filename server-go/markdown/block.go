@@ -0,0 +1,519 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// line 一行原文，num 为在文件中的行号
+type line struct {
+	text string
+	num  int
+}
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	codeBlock
+	mermaidBlock
+	htmlBlock
+	moreBlock
+	quoteBlock
+	listBlock
+	itemBlock
+	tableBlock
+	ruleBlock
+)
+
+// block 块级元素
+type block struct {
+	kind     blockKind
+	line     int // 起止行号
+	end      int
+	level    int    // 标题级别
+	text     string // 段落、标题的行内内容；代码块和 HTML 的原文
+	info     string // 代码块语言
+	children []*block
+
+	ordered bool // 列表
+	start   int
+	tight   bool
+	task    int // 列表项：0 普通，1 未完成，2 已完成
+
+	align []string   // 表格各列的对齐方式
+	rows  [][]string // 表格单元格，第一行为表头
+
+	id   string // 标题：{#锚点} 指定的锚点
+	term bool   // 标题：带 {.term}，标题本身作为词条
+}
+
+// linkRef 链接引用定义 [名称]: 地址 "标题"
+type linkRef struct {
+	dest, title string
+}
+
+var (
+	atxHeadingRe  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextRe      = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	headingAttrRe = regexp.MustCompile(`[ \t]*\{[ \t]*([#.][^\s{}]+(?:[ \t]+[#.][^\s{}]+)*)[ \t]*\}$`)
+	ruleRe        = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRe       = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*(.*)$")
+	quoteRe       = regexp.MustCompile(`^ {0,3}> ?`)
+	listItemRe    = regexp.MustCompile(`^( {0,3})([-+*]|\d{1,9}[.)])( +|$)`)
+	taskRe        = regexp.MustCompile(`^\[([ xX])\](?:[ \t]+|$)`)
+	moreRe        = regexp.MustCompile(`(?i)^ {0,3}<!--\s*more\s*-->\s*$`)
+	refDefRe      = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:[ \t]*(<[^>\n]*>|\S+)(?:[ \t]+("[^"]*"|'[^']*'|\([^)]*\)))?[ \t]*$`)
+	tableDelimRe  = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+
+	// HTML 块：类型 1（到结束标签）、2（注释）、6（块级标签）、7（单独一行的完整标签）
+	htmlRawRe     = regexp.MustCompile(`(?i)^ {0,3}<(script|pre|style|textarea)(?:\s|>|$)`)
+	htmlCommentRe = regexp.MustCompile(`^ {0,3}<!--`)
+	htmlBlockRe   = regexp.MustCompile(`(?i)^ {0,3}</?(address|article|aside|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[1-6]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(?:\s|/?>|$)`)
+	htmlTagLineRe = regexp.MustCompile(`^ {0,3}(?:<[a-zA-Z][a-zA-Z0-9-]*(?:\s+[a-zA-Z_:][a-zA-Z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[a-zA-Z][a-zA-Z0-9-]*\s*>)[ \t]*$`)
+)
+
+type blockParser struct {
+	refs map[string]linkRef
+}
+
+// heading 创建标题块，拆出末尾的 {#锚点 .term} 属性
+func heading(num, level int, text string) *block {
+	b := &block{kind: headingBlock, line: num, level: level}
+	text = strings.TrimSpace(text)
+	if m := headingAttrRe.FindStringSubmatchIndex(text); m != nil {
+		for _, attr := range strings.Fields(text[m[2]:m[3]]) {
+			switch {
+			case attr[0] == '#':
+				b.id = attr[1:]
+			case attr == ".term":
+				b.term = true
+			}
+		}
+		text = strings.TrimSpace(text[:m[0]])
+	}
+	b.text = text
+	return b
+}
+
+// parse 解析一段连续的行（文档正文、引用块或列表项的内容）
+func (p *blockParser) parse(lines []line) []*block {
+	var blocks []*block
+	for i := 0; i < len(lines); {
+		l, start, count := lines[i], i, len(blocks)
+		switch {
+		case isBlank(l.text):
+			i++
+
+		case indentOf(l.text) >= 4:
+			i = p.indentedCode(lines, i, &blocks)
+
+		case fenceRe.MatchString(l.text) && validFence(l.text):
+			i = p.fencedCode(lines, i, &blocks)
+
+		case atxHeadingRe.MatchString(l.text):
+			m := atxHeadingRe.FindStringSubmatch(l.text)
+			blocks = append(blocks, heading(l.num, len(m[1]), m[2]))
+			i++
+
+		case ruleRe.MatchString(l.text):
+			blocks = append(blocks, &block{kind: ruleBlock, line: l.num})
+			i++
+
+		case quoteRe.MatchString(l.text):
+			i = p.quote(lines, i, &blocks)
+
+		case moreRe.MatchString(l.text):
+			blocks = append(blocks, &block{kind: moreBlock, line: l.num})
+			i++
+
+		case htmlStart(l.text, false):
+			i = p.html(lines, i, &blocks)
+
+		case listItemRe.MatchString(l.text):
+			i = p.list(lines, i, &blocks)
+
+		case i+1 < len(lines) && isTableStart(l.text, lines[i+1].text):
+			i = p.table(lines, i, &blocks)
+
+		default:
+			i = p.paragraph(lines, i, &blocks)
+		}
+
+		if len(blocks) > count {
+			end := i - 1
+			for end > start && isBlank(lines[end].text) {
+				end--
+			}
+			blocks[len(blocks)-1].end = lines[end].num
+		}
+	}
+	return blocks
+}
+
+func (p *blockParser) indentedCode(lines []line, i int, blocks *[]*block) int {
+	start := i
+	var code []string
+	for ; i < len(lines); i++ {
+		text := lines[i].text
+		if !isBlank(text) && indentOf(text) < 4 {
+			break
+		}
+		code = append(code, removeIndent(text, 4))
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+		i--
+	}
+	*blocks = append(*blocks, &block{kind: codeBlock, line: lines[start].num, text: strings.Join(code, "\n") + "\n"})
+	return i
+}
+
+func (p *blockParser) fencedCode(lines []line, i int, blocks *[]*block) int {
+	m := fenceRe.FindStringSubmatch(lines[i].text)
+	indent, fence := len(m[1]), m[2]
+	info := strings.TrimSpace(m[3])
+
+	b := &block{kind: codeBlock, line: lines[i].num, info: info}
+	if lang, _, _ := strings.Cut(info, " "); lang == "mermaid" {
+		b.kind = mermaidBlock
+	}
+	var code []string
+	for i++; i < len(lines); i++ {
+		if closesFence(lines[i].text, fence) {
+			i++
+			break
+		}
+		code = append(code, removeIndent(lines[i].text, indent))
+	}
+	if len(code) > 0 {
+		b.text = strings.Join(code, "\n") + "\n"
+	}
+	*blocks = append(*blocks, b)
+	return i
+}
+
+func (p *blockParser) quote(lines []line, i int, blocks *[]*block) int {
+	start := i
+	var inner []line
+	lazy := false // 上一行是段落，不带 > 的下一行仍属于该段落
+	for ; i < len(lines); i++ {
+		text := lines[i].text
+		if loc := quoteRe.FindStringIndex(text); loc != nil {
+			rest := text[loc[1]:]
+			inner = append(inner, line{text: rest, num: lines[i].num})
+			lazy = !isBlank(rest) && !startsBlock(rest)
+			continue
+		}
+		if lazy && !isBlank(text) && !startsBlock(text) {
+			inner = append(inner, lines[i])
+			continue
+		}
+		break
+	}
+	*blocks = append(*blocks, &block{kind: quoteBlock, line: lines[start].num, children: p.parse(inner)})
+	return i
+}
+
+func (p *blockParser) html(lines []line, i int, blocks *[]*block) int {
+	start := i
+	text := lines[i].text
+	var end func(string) bool
+	switch {
+	case htmlRawRe.MatchString(text):
+		tag := strings.ToLower(htmlRawRe.FindStringSubmatch(text)[1])
+		end = func(s string) bool { return strings.Contains(strings.ToLower(s), "</"+tag+">") }
+	case htmlCommentRe.MatchString(text):
+		end = func(s string) bool { return strings.Contains(s, "-->") }
+	}
+
+	var raw []string
+	for ; i < len(lines); i++ {
+		text := lines[i].text
+		if end == nil && isBlank(text) {
+			break
+		}
+		raw = append(raw, text)
+		if end != nil && end(text) {
+			i++
+			break
+		}
+	}
+	*blocks = append(*blocks, &block{kind: htmlBlock, line: lines[start].num, text: strings.Join(raw, "\n")})
+	return i
+}
+
+func (p *blockParser) list(lines []line, i int, blocks *[]*block) int {
+	first := listItemRe.FindStringSubmatch(lines[i].text)
+	list := &block{kind: listBlock, line: lines[i].num, tight: true}
+	marker := first[2]
+	if list.ordered = marker[0] >= '0' && marker[0] <= '9'; list.ordered {
+		list.start, _ = strconv.Atoi(marker[:len(marker)-1])
+	}
+	sameList := func(m []string) bool {
+		if list.ordered {
+			return m[2][len(m[2])-1] == marker[len(marker)-1]
+		}
+		return m[2] == marker
+	}
+
+	for i < len(lines) {
+		m := listItemRe.FindStringSubmatch(lines[i].text)
+		if m == nil || !sameList(m) || ruleRe.MatchString(lines[i].text) {
+			break
+		}
+
+		// 内容缩进：标记后的空格超过 4 个时视为缩进代码，只计 1 个
+		width := len(m[1]) + len(m[2]) + len(m[3])
+		if len(m[3]) > 4 || len(m[3]) == 0 {
+			width = len(m[1]) + len(m[2]) + 1
+		}
+		item := &block{kind: itemBlock, line: lines[i].num}
+		text := lines[i].text
+		content := []line{{text: text[min(width, len(text)):], num: lines[i].num}}
+
+		lazy := !isBlank(content[0].text) && !startsBlock(content[0].text)
+		for i++; i < len(lines); i++ {
+			text := lines[i].text
+			switch {
+			case isBlank(text):
+				content = append(content, line{text: "", num: lines[i].num})
+				lazy = false
+				continue
+			case indentOf(text) >= width:
+				rest := removeIndent(text, width)
+				content = append(content, line{text: rest, num: lines[i].num})
+				lazy = !startsBlock(rest)
+				continue
+			case lazy && !startsBlock(text):
+				content = append(content, lines[i])
+				continue
+			}
+			break
+		}
+
+		// 列表项之间或列表项内部的块之间有空行时为松散列表
+		trailing := 0
+		for len(content) > 1 && isBlank(content[len(content)-1].text) {
+			content = content[:len(content)-1]
+			trailing++
+		}
+		item.children = p.parse(content)
+		if trailing > 0 && i < len(lines) {
+			if next := listItemRe.FindStringSubmatch(lines[i].text); next != nil && sameList(next) {
+				list.tight = false
+			}
+		}
+		for k := 1; k < len(item.children); k++ {
+			for _, l := range content {
+				if l.num == item.children[k].line-1 && isBlank(l.text) {
+					list.tight = false
+				}
+			}
+		}
+
+		if len(item.children) > 0 && item.children[0].kind == paragraphBlock {
+			if t := taskRe.FindStringSubmatch(item.children[0].text); t != nil {
+				item.task = 1
+				if t[1] != " " {
+					item.task = 2
+				}
+				item.children[0].text = item.children[0].text[len(t[0]):]
+			}
+		}
+		list.children = append(list.children, item)
+	}
+	*blocks = append(*blocks, list)
+	return i
+}
+
+func (p *blockParser) table(lines []line, i int, blocks *[]*block) int {
+	header := splitRow(lines[i].text)
+	t := &block{kind: tableBlock, line: lines[i].num, rows: [][]string{header}}
+	for _, cell := range splitRow(lines[i+1].text) {
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			t.align = append(t.align, "center")
+		case left:
+			t.align = append(t.align, "left")
+		case right:
+			t.align = append(t.align, "right")
+		default:
+			t.align = append(t.align, "")
+		}
+	}
+	for i += 2; i < len(lines); i++ {
+		text := lines[i].text
+		if isBlank(text) || !strings.Contains(text, "|") || startsBlock(text) {
+			break
+		}
+		row := splitRow(text)
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		t.rows = append(t.rows, row[:len(header)])
+	}
+	*blocks = append(*blocks, t)
+	return i
+}
+
+func (p *blockParser) paragraph(lines []line, i int, blocks *[]*block) int {
+	start := i
+	var text []string
+	for ; i < len(lines); i++ {
+		l := lines[i].text
+		if i > start {
+			if isBlank(l) {
+				break
+			}
+			if m := setextRe.FindStringSubmatch(l); m != nil && indentOf(l) < 4 {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				text = p.takeRefs(text)
+				if len(text) == 0 {
+					// 全部是链接引用定义，--- 作为分隔线重新解析
+					return i
+				}
+				*blocks = append(*blocks, heading(lines[start].num, level, strings.Join(text, "\n")))
+				return i + 1
+			}
+			if interruptsParagraph(l) || (i+1 < len(lines) && isTableStart(l, lines[i+1].text)) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(l, " "))
+	}
+
+	text = p.takeRefs(text)
+	if len(text) > 0 {
+		*blocks = append(*blocks, &block{kind: paragraphBlock, line: lines[start].num, text: strings.TrimRight(strings.Join(text, "\n"), " \t")})
+	}
+	return i
+}
+
+// takeRefs 取出段落开头的链接引用定义
+func (p *blockParser) takeRefs(text []string) []string {
+	for len(text) > 0 {
+		m := refDefRe.FindStringSubmatch(text[0])
+		if m == nil {
+			break
+		}
+		key := normalizeLabel(m[1])
+		if _, ok := p.refs[key]; !ok {
+			dest := strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")
+			title := ""
+			if len(m[3]) >= 2 {
+				title = m[3][1 : len(m[3])-1]
+			}
+			p.refs[key] = linkRef{dest: dest, title: title}
+		}
+		text = text[1:]
+	}
+	return text
+}
+
+// startsBlock 该行是否开始一个新的块（用于判断引用块、列表中不带前缀的行是否延续段落）
+func startsBlock(text string) bool {
+	return atxHeadingRe.MatchString(text) || ruleRe.MatchString(text) || quoteRe.MatchString(text) ||
+		(fenceRe.MatchString(text) && validFence(text)) || htmlStart(text, true) || listItemRe.MatchString(text)
+}
+
+// interruptsParagraph 该行是否结束前面的段落
+func interruptsParagraph(text string) bool {
+	if atxHeadingRe.MatchString(text) || ruleRe.MatchString(text) || quoteRe.MatchString(text) ||
+		(fenceRe.MatchString(text) && validFence(text)) || htmlStart(text, true) {
+		return true
+	}
+	// 只有以 1 开头的非空有序列表、非空无序列表可以打断段落
+	m := listItemRe.FindStringSubmatch(text)
+	if m == nil || isBlank(text[len(m[0]):]) {
+		return false
+	}
+	if m[2][0] >= '0' && m[2][0] <= '9' {
+		return m[2][:len(m[2])-1] == "1"
+	}
+	return true
+}
+
+// htmlStart 该行是否开始 HTML 块；inParagraph 时单独一行的任意标签不打断段落
+func htmlStart(text string, inParagraph bool) bool {
+	if htmlRawRe.MatchString(text) || htmlCommentRe.MatchString(text) || htmlBlockRe.MatchString(text) {
+		return true
+	}
+	return !inParagraph && htmlTagLineRe.MatchString(text)
+}
+
+// closesFence 判断是否为 fence 的结束行：最多 3 个空格缩进，
+// 与开始行相同的字符且不少于开始行的个数，后面只有空白
+func closesFence(text, fence string) bool {
+	rest := strings.TrimLeft(text, " ")
+	if len(text)-len(rest) > 3 {
+		return false
+	}
+	n := len(rest) - len(strings.TrimLeft(rest, fence[:1]))
+	return n >= len(fence) && strings.TrimRight(rest[n:], " \t") == ""
+}
+
+// validFence ``` 代码块的语言信息中不能包含反引号
+func validFence(text string) bool {
+	m := fenceRe.FindStringSubmatch(text)
+	return m[2][0] != '`' || !strings.Contains(m[3], "`")
+}
+
+func isTableStart(header, delim string) bool {
+	if !strings.Contains(header, "|") || !tableDelimRe.MatchString(delim) {
+		return false
+	}
+	return len(splitRow(header)) == len(splitRow(delim))
+}
+
+// splitRow 按未转义的 | 拆分表格行
+func splitRow(text string) []string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "|")
+	if strings.HasSuffix(text, "|") && !strings.HasSuffix(text, `\|`) {
+		text = text[:len(text)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && text[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case text[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(text[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func isBlank(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
+
+func indentOf(text string) int {
+	return len(text) - len(strings.TrimLeft(text, " "))
+}
+
+// removeIndent 去掉行首至多 n 个空格
+func removeIndent(text string, n int) string {
+	i := 0
+	for i < n && i < len(text) && text[i] == ' ' {
+		i++
+	}
+	return text[i:]
+}
+
+// normalizeLabel 链接引用名称不区分大小写，连续空白视为一个空格
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
@@ -0,0 +1,126 @@
+package markdown
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Heading 文档的标题及其章节范围
+type Heading struct {
+	Level int
+	// Text 标题的纯文本，不含 {#锚点 .term} 属性
+	Text string
+	// Slug 锚点，{#锚点} 指定或由标题生成，文档内唯一
+	Slug string
+	// Line 标题所在行，End 为章节最后一个非空行（下一个同级或更高级标题之前）
+	Line int
+	End  int
+	// Term 标题带 {.term}，标题本身可作为词条引用
+	Term bool
+}
+
+// Section 章节：标题及其定义摘要
+type Section struct {
+	Heading
+	// Summary 标题之后到 <!-- more --> 之间的内容，规则同 Summarize
+	Summary Summary
+}
+
+// Headings 文档中的标题，按出现顺序；引用块、列表中的标题不算章节
+func Headings(text string) []Heading {
+	return parseOutline(text).headings
+}
+
+// Sections 文档的章节及各章节的定义摘要，整篇文档只解析一次
+func Sections(text string, limit int) []Section {
+	o := parseOutline(text)
+	sections := make([]Section, len(o.headings))
+	for i, h := range o.headings {
+		sections[i] = Section{Heading: h, Summary: o.p.summarize(o.lines, o.blocks[o.index[i]+1:], h.End, limit)}
+	}
+	return sections
+}
+
+// outline 解析一次的文档：行、顶层块和标题，index 为各标题在 blocks 中的位置
+type outline struct {
+	p        *blockParser
+	lines    []line
+	blocks   []*block
+	headings []Heading
+	index    []int
+}
+
+func parseOutline(text string) *outline {
+	p := &blockParser{refs: make(map[string]linkRef)}
+	o := &outline{p: p, lines: documentLines(text)}
+	o.blocks = p.parse(o.lines)
+	slugs := p.headingSlugs(o.blocks)
+	for i, b := range o.blocks {
+		if b.kind == headingBlock {
+			o.headings = append(o.headings, Heading{Level: b.level, Text: p.plain(b), Slug: slugs[b], Line: b.line, Term: b.term})
+			o.index = append(o.index, i)
+		}
+	}
+
+	// 从后向前计算章节范围：ends[l] 为 l 级章节到此为止的最后一行，0 表示之后紧接着同级或更高级的标题
+	var ends [7]int
+	h := len(o.headings) - 1
+	for i := len(o.blocks) - 1; i >= 0; i-- {
+		b := o.blocks[i]
+		level := len(ends) // 普通块属于所有级别的章节
+		if b.kind == headingBlock {
+			level = b.level
+			o.headings[h].End = cmp.Or(ends[level], b.end)
+			h--
+		}
+		for l := 1; l < len(ends); l++ {
+			if l >= level {
+				ends[l] = 0
+			} else if ends[l] == 0 {
+				ends[l] = b.end
+			}
+		}
+	}
+	return o
+}
+
+// Slug 由标题生成锚点：保留字母、数字、- 和 _，空白替换为 -，英文转小写
+func Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// headingSlugs 顶层标题的锚点，重复的依次加 -1、-2 后缀
+func (p *blockParser) headingSlugs(blocks []*block) map[*block]string {
+	slugs := make(map[*block]string)
+	used := make(map[string]int)
+	for _, b := range blocks {
+		if b.kind != headingBlock {
+			continue
+		}
+		slug := b.id
+		if slug == "" {
+			slug = Slug(p.plain(b))
+		}
+		if slug == "" {
+			slug = "section"
+		}
+		if n, ok := used[slug]; ok {
+			used[slug] = n + 1
+			slug += "-" + strconv.Itoa(n+1)
+		}
+		used[slug] = 0
+		slugs[b] = slug
+	}
+	return slugs
+}
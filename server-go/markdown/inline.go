@@ -0,0 +1,661 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type nodeKind int
+
+const (
+	textNode nodeKind = iota
+	rawNode           // 原样输出的 HTML 和实体
+	codeNode
+	softBreakNode
+	hardBreakNode
+	emNode
+	strongNode
+	delNode
+	linkNode
+	imageNode
+	termNode
+	definitionNode
+	formulaNode
+)
+
+// node 行内元素
+type node struct {
+	kind     nodeKind
+	text     string // 文本、代码、HTML；链接地址；词条名；公式
+	title    string // 链接标题；定义内容
+	children []*node
+
+	// 解析过程中元素组成双向链表，first 为子元素链表的开头，解析结束时转为 children
+	prev, next, first *node
+}
+
+// delim 尚未配对的 * _ ~ 分隔符，组成双向链表（分隔符栈）
+type delim struct {
+	n                 *node
+	ch                byte
+	count, orig       int
+	canOpen, canClose bool
+	prev, next        *delim
+}
+
+// bracket 尚未配对的 [ 或 ![
+type bracket struct {
+	n      *node
+	pos    int // [ 之后在原文中的位置
+	image  bool
+	active bool
+	bottom *delim // 之前的分隔符栈顶
+}
+
+// openerKey 查找开始分隔符时按结束分隔符区分的类别
+type openerKey struct {
+	ch      byte
+	canOpen bool
+	mod     int
+}
+
+var (
+	autolinkRe  = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailRe     = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	htmlTagRe   = regexp.MustCompile(`^(?:<[a-zA-Z][a-zA-Z0-9-]*(?:\s+[a-zA-Z_:][a-zA-Z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[a-zA-Z][a-zA-Z0-9-]*\s*>|(?s:<!--.*?-->))`)
+	entityRe    = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+	urlRe       = regexp.MustCompile(`^(?:https?://|www\.)[\x21-\x7e]+`)
+	formulaRe   = regexp.MustCompile(`^%%\s*(.+?)\s*%%`)
+	termRe      = regexp.MustCompile(`^【([^】{}\n]+)】`)
+	nextDefRe   = regexp.MustCompile(`【[^】\n]+】：`)
+	dataImageRe = regexp.MustCompile(`^data:image/(?:gif|png|jpeg|webp);`)
+)
+
+type inlineParser struct {
+	src        string
+	pos        int
+	refs       map[string]linkRef
+	head, tail *node
+	lastDelim  *delim
+	brackets   []*bracket
+}
+
+// parseInline 解析段落、标题、表格单元格中的行内内容
+func parseInline(src string, refs map[string]linkRef) []*node {
+	p := &inlineParser{src: src, refs: refs}
+	for p.pos < len(p.src) {
+		p.next()
+	}
+	p.processEmphasis(nil)
+	return collect(p.head)
+}
+
+func (p *inlineParser) add(kind nodeKind, text string) *node {
+	return p.push(&node{kind: kind, text: text})
+}
+
+// push 将元素追加到链表末尾
+func (p *inlineParser) push(n *node) *node {
+	n.prev = p.tail
+	if p.tail != nil {
+		p.tail.next = n
+	} else {
+		p.head = n
+	}
+	p.tail = n
+	return n
+}
+
+// unlink 从链表中移除元素
+func (p *inlineParser) unlink(n *node) {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		p.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		p.tail = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
+
+// collect 将链表转为切片，子元素链表一并转换
+func collect(first *node) []*node {
+	var nodes []*node
+	for n := first; n != nil; n = n.next {
+		if n.first != nil {
+			n.children, n.first = collect(n.first), nil
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func (p *inlineParser) next() {
+	rest := p.src[p.pos:]
+	switch rest[0] {
+	case '\\':
+		if len(rest) > 1 && rest[1] == '\n' {
+			p.add(hardBreakNode, "")
+			p.pos += 2
+			p.skipSpaces()
+			return
+		}
+		if len(rest) > 1 && isASCIIPunct(rest[1]) {
+			p.add(textNode, rest[1:2])
+			p.pos += 2
+			return
+		}
+	case '`':
+		if p.codeSpan() {
+			return
+		}
+	case '<':
+		if m := autolinkRe.FindStringSubmatch(rest); m != nil {
+			p.push(&node{kind: linkNode, text: m[1], children: []*node{{kind: textNode, text: m[1]}}})
+			p.pos += len(m[0])
+			return
+		}
+		if m := emailRe.FindStringSubmatch(rest); m != nil {
+			p.push(&node{kind: linkNode, text: "mailto:" + m[1], children: []*node{{kind: textNode, text: m[1]}}})
+			p.pos += len(m[0])
+			return
+		}
+		if m := htmlTagRe.FindString(rest); m != "" {
+			p.add(rawNode, m)
+			p.pos += len(m)
+			return
+		}
+	case '&':
+		if m := entityRe.FindString(rest); m != "" {
+			p.add(rawNode, m)
+			p.pos += len(m)
+			return
+		}
+	case '%':
+		if m := formulaRe.FindStringSubmatch(rest); m != nil {
+			p.add(formulaNode, m[1])
+			p.pos += len(m[0])
+			return
+		}
+	case '!':
+		if len(rest) > 1 && rest[1] == '[' {
+			p.brackets = append(p.brackets, &bracket{n: p.add(textNode, "!["), pos: p.pos + 2, image: true, active: true, bottom: p.lastDelim})
+			p.pos += 2
+			return
+		}
+	case '[':
+		p.brackets = append(p.brackets, &bracket{n: p.add(textNode, "["), pos: p.pos + 1, active: true, bottom: p.lastDelim})
+		p.pos++
+		return
+	case ']':
+		p.closeBracket()
+		return
+	case '*', '_', '~':
+		p.delimRun()
+		return
+	case '\n':
+		p.lineBreak()
+		return
+	}
+	if strings.HasPrefix(rest, "【") && p.wikiTerm() {
+		return
+	}
+	if p.linkify() {
+		return
+	}
+	p.text()
+}
+
+// text 输出到下一个可能有特殊含义的字符之前的文本
+func (p *inlineParser) text() {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		i := strings.IndexAny(p.src[p.pos:], "\\`<&%![]*_~\n【hw")
+		if i < 0 {
+			p.pos = len(p.src)
+			break
+		}
+		p.pos += i
+		c := p.src[p.pos]
+		if (c == 'h' || c == 'w') && !p.urlStart() {
+			p.pos++
+			continue
+		}
+		break
+	}
+	p.add(textNode, p.src[start:p.pos])
+}
+
+func (p *inlineParser) codeSpan() bool {
+	n := 0
+	for p.pos+n < len(p.src) && p.src[p.pos+n] == '`' {
+		n++
+	}
+	ticks := p.src[p.pos : p.pos+n]
+	for i := p.pos + n; i < len(p.src); {
+		j := strings.Index(p.src[i:], ticks)
+		if j < 0 {
+			break
+		}
+		j += i
+		end := j + n
+		if end < len(p.src) && p.src[end] == '`' {
+			// 长度不同的反引号串
+			for end < len(p.src) && p.src[end] == '`' {
+				end++
+			}
+			i = end
+			continue
+		}
+		code := strings.ReplaceAll(p.src[p.pos+n:j], "\n", " ")
+		if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+		p.add(codeNode, code)
+		p.pos = end
+		return true
+	}
+	p.add(textNode, ticks)
+	p.pos += n
+	return true
+}
+
+// wikiTerm 【词条】引用或 【词条】：定义
+func (p *inlineParser) wikiTerm() bool {
+	rest := p.src[p.pos:]
+	m := termRe.FindStringSubmatch(rest)
+	if m == nil {
+		return false
+	}
+	name := strings.TrimSpace(m[1])
+	after := rest[len(m[0]):]
+	if !strings.HasPrefix(after, "：") {
+		p.add(termNode, name)
+		p.pos += len(m[0])
+		return true
+	}
+
+	// 定义内容到行尾或下一个 【词条】：
+	def := after[len("："):]
+	if i := strings.IndexByte(def, '\n'); i >= 0 {
+		def = def[:i]
+	}
+	if loc := nextDefRe.FindStringIndex(def); loc != nil {
+		def = def[:loc[0]]
+	}
+	p.push(&node{kind: definitionNode, text: name, title: unescape(strings.TrimSpace(def))})
+	p.pos += len(m[0]) + len("：") + len(def)
+	return true
+}
+
+// urlStart 当前位置是否为裸链接的开头
+func (p *inlineParser) urlStart() bool {
+	if p.inLinkText() {
+		return false
+	}
+	if p.pos > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(p.src[:p.pos])
+		if prev < utf8.RuneSelf && (unicode.IsLetter(prev) || unicode.IsDigit(prev)) {
+			return false
+		}
+	}
+	return urlRe.MatchString(p.src[p.pos:])
+}
+
+// inLinkText 是否在 [链接文字] 中
+func (p *inlineParser) inLinkText() bool {
+	for _, b := range p.brackets {
+		if !b.image && b.active {
+			return true
+		}
+	}
+	return false
+}
+
+// linkify 将裸链接 https://… 转为链接
+func (p *inlineParser) linkify() bool {
+	if !p.urlStart() {
+		return false
+	}
+	url := urlRe.FindString(p.src[p.pos:])
+	url = strings.TrimRight(url, `?!.,:;*_~'"`)
+	for strings.HasSuffix(url, ")") && strings.Count(url, "(") < strings.Count(url, ")") {
+		url = url[:len(url)-1]
+	}
+	if strings.HasPrefix(url, "www.") && len(url) <= 4 || strings.HasSuffix(url, "://") {
+		return false
+	}
+	href := url
+	if strings.HasPrefix(url, "www.") {
+		href = "http://" + url
+	}
+	p.push(&node{kind: linkNode, text: href, children: []*node{{kind: textNode, text: url}}})
+	p.pos += len(url)
+	return true
+}
+
+func (p *inlineParser) lineBreak() {
+	kind := softBreakNode
+	if last := p.tail; last != nil && last.kind == textNode {
+		trimmed := strings.TrimRight(last.text, " ")
+		if len(last.text)-len(trimmed) >= 2 {
+			kind = hardBreakNode
+		}
+		last.text = trimmed
+	}
+	p.add(kind, "")
+	p.pos++
+	p.skipSpaces()
+}
+
+func (p *inlineParser) skipSpaces() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *inlineParser) delimRun() {
+	ch := p.src[p.pos]
+	end := p.pos
+	for end < len(p.src) && p.src[end] == ch {
+		end++
+	}
+	run := p.src[p.pos:end]
+
+	prev, next := ' ', ' '
+	if p.pos > 0 {
+		prev, _ = utf8.DecodeLastRuneInString(p.src[:p.pos])
+	}
+	if end < len(p.src) {
+		next, _ = utf8.DecodeRuneInString(p.src[end:])
+	}
+	left := !unicode.IsSpace(next) && (!isPunct(next) || unicode.IsSpace(prev) || isPunct(prev))
+	right := !unicode.IsSpace(prev) && (!isPunct(prev) || unicode.IsSpace(next) || isPunct(next))
+
+	n := p.add(textNode, run)
+	p.pos = end
+	d := &delim{n: n, ch: ch, count: len(run), orig: len(run), canOpen: left, canClose: right}
+	switch ch {
+	case '_':
+		d.canOpen = left && (!right || isPunct(prev))
+		d.canClose = right && (!left || isPunct(next))
+	case '~':
+		// 删除线只用 ~~
+		if len(run) != 2 {
+			return
+		}
+	}
+	if d.canOpen || d.canClose {
+		d.prev = p.lastDelim
+		if p.lastDelim != nil {
+			p.lastDelim.next = d
+		}
+		p.lastDelim = d
+	}
+}
+
+func (p *inlineParser) closeBracket() {
+	n := len(p.brackets)
+	if n == 0 {
+		p.add(textNode, "]")
+		p.pos++
+		return
+	}
+	b := p.brackets[n-1]
+	p.brackets = p.brackets[:n-1]
+	if !b.active {
+		p.add(textNode, "]")
+		p.pos++
+		return
+	}
+
+	dest, title, end, ok := p.linkTail(p.pos + 1)
+	if !ok {
+		dest, title, end, ok = p.linkRef(b)
+	}
+	if ok && !validLink(dest, b.image) {
+		ok = false
+	}
+	if !ok {
+		p.add(textNode, "]")
+		p.pos++
+		return
+	}
+
+	p.processEmphasis(b.bottom)
+	kind := linkNode
+	if b.image {
+		kind = imageNode
+	} else {
+		// 链接中不能再有链接
+		for _, ob := range p.brackets {
+			if !ob.image {
+				ob.active = false
+			}
+		}
+	}
+	// [ 之后的元素成为链接的子元素
+	link := &node{kind: kind, text: dest, title: title, first: b.n.next}
+	if link.first != nil {
+		link.first.prev = nil
+		b.n.next, p.tail = nil, b.n
+	}
+	p.unlink(b.n)
+	p.push(link)
+	p.pos = end
+}
+
+// linkTail 解析 ](地址 "标题") 中 ] 之后的部分，i 为 ( 的位置
+func (p *inlineParser) linkTail(i int) (dest, title string, end int, ok bool) {
+	s := p.src
+	if i >= len(s) || s[i] != '(' {
+		return "", "", 0, false
+	}
+	i = skipSpace(s, i+1)
+	if i < len(s) && s[i] == '<' {
+		j := strings.IndexAny(s[i+1:], ">\n")
+		if j < 0 || s[i+1+j] != '>' {
+			return "", "", 0, false
+		}
+		dest = s[i+1 : i+1+j]
+		i += j + 2
+	} else {
+		start, depth := i, 0
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+				i++
+				continue
+			}
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			} else if c <= ' ' {
+				break
+			}
+		}
+		dest = unescape(s[start:i])
+	}
+
+	j := skipSpace(s, i)
+	if j < len(s) && j > i && (s[j] == '"' || s[j] == '\'' || s[j] == '(') {
+		closer := s[j]
+		if closer == '(' {
+			closer = ')'
+		}
+		k := strings.IndexByte(s[j+1:], closer)
+		if k < 0 {
+			return "", "", 0, false
+		}
+		title = unescape(s[j+1 : j+1+k])
+		j = skipSpace(s, j+2+k)
+	}
+	if j >= len(s) || s[j] != ')' {
+		return "", "", 0, false
+	}
+	return dest, title, j + 1, true
+}
+
+// linkRef 解析 [文字][名称]、[文字][]、[名称] 形式的引用链接
+func (p *inlineParser) linkRef(b *bracket) (dest, title string, end int, ok bool) {
+	label := p.src[b.pos:p.pos]
+	end = p.pos + 1
+	if end < len(p.src) && p.src[end] == '[' {
+		if j := strings.IndexByte(p.src[end+1:], ']'); j >= 0 {
+			if j > 0 {
+				label = p.src[end+1 : end+1+j]
+			}
+			end += j + 2
+		}
+	}
+	ref, ok := p.refs[normalizeLabel(label)]
+	return ref.dest, ref.title, end, ok
+}
+
+// processEmphasis 按 CommonMark 的分隔符栈算法配对 bottom 之上的分隔符，生成强调、加粗和删除线，
+// 处理完后将这些分隔符出栈；每次配对只调整链表指针，耗时与分隔符数量成线性
+func (p *inlineParser) processEmphasis(bottom *delim) {
+	var closer *delim
+	for d := p.lastDelim; d != bottom; d = d.prev {
+		closer = d
+	}
+	// 同类结束分隔符已查找过的下界，之下不会再有可配对的开始分隔符
+	openersBottom := make(map[openerKey]*delim)
+
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+		key := openerKey{closer.ch, closer.canOpen, closer.orig % 3}
+		limit, ok := openersBottom[key]
+		if !ok {
+			limit = bottom
+		}
+		opener := closer.prev
+		for ; opener != bottom && opener != limit; opener = opener.prev {
+			if opener.ch == closer.ch && opener.canOpen && !multipleOfThree(opener, closer) {
+				break
+			}
+		}
+		if opener == bottom || opener == limit {
+			openersBottom[key] = closer.prev
+			next := closer.next
+			if !closer.canOpen {
+				p.removeDelim(closer)
+			}
+			closer = next
+			continue
+		}
+
+		use, kind := 1, emNode
+		switch {
+		case closer.ch == '~':
+			use, kind = 2, delNode
+		case opener.count >= 2 && closer.count >= 2:
+			use, kind = 2, strongNode
+		}
+		opener.count -= use
+		opener.n.text = opener.n.text[:opener.count]
+		closer.count -= use
+		closer.n.text = closer.n.text[:closer.count]
+		wrap(opener.n, closer.n, kind)
+
+		// 中间未配对的分隔符作为普通文本
+		opener.next, closer.prev = closer, opener
+		if opener.count == 0 {
+			p.unlink(opener.n)
+			p.removeDelim(opener)
+		}
+		if closer.count == 0 {
+			next := closer.next
+			p.unlink(closer.n)
+			p.removeDelim(closer)
+			closer = next
+		}
+	}
+
+	p.lastDelim = bottom
+	if bottom != nil {
+		bottom.next = nil
+	}
+}
+
+// multipleOfThree 同时可开可闭的分隔符，长度之和为 3 的倍数时不能配对
+func multipleOfThree(opener, closer *delim) bool {
+	return closer.ch != '~' && (opener.canClose || closer.canOpen) &&
+		(opener.orig+closer.orig)%3 == 0 && !(opener.orig%3 == 0 && closer.orig%3 == 0)
+}
+
+// wrap 将 opener 与 closer 之间的元素包进一个 kind 元素
+func wrap(opener, closer *node, kind nodeKind) {
+	w := &node{kind: kind, prev: opener, next: closer}
+	if first := opener.next; first != closer {
+		last := closer.prev
+		first.prev, last.next = nil, nil
+		w.first = first
+	}
+	opener.next, closer.prev = w, w
+}
+
+func (p *inlineParser) removeDelim(d *delim) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	}
+	if d.next != nil {
+		d.next.prev = d.prev
+	} else {
+		p.lastDelim = d.prev
+	}
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// unescape 去掉反斜杠转义
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// validLink 与 markdown-it 一样拒绝 javascript: 等地址，图片允许 data:image/
+func validLink(dest string, image bool) bool {
+	d := strings.ToLower(strings.TrimSpace(dest))
+	for _, scheme := range []string{"javascript:", "vbscript:", "file:", "data:"} {
+		if strings.HasPrefix(d, scheme) {
+			return image && dataImageRe.MatchString(d)
+		}
+	}
+	return true
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,102 @@
+// Package markdown 将 wiki 文档渲染为 HTML，供静态导出、邮件摘要等不经过前端的场景使用
+//
+// 支持 CommonMark 和 GFM 的常用语法（表格、删除线、任务列表、自动链接），以及 wiki 语法：
+//
+//	【词条】          <span class="wiki-term" data-term="词条">
+//	【词条】：定义    <span class="wiki-definition" data-term="词条" data-definition="定义">
+//	%% 公式 %%       <span class="wiki-formula" data-formula="公式">，计算值和设计值分别标出
+//	```mermaid       <pre class="mermaid">，可直接交给 mermaid.js 渲染
+//	<!-- more -->    <div class="wiki-more">，Summary 模式在此处截断
+//
+// 块级元素带有 data-line 属性，为其在原文件中的行号（从 1 开始，计入 frontmatter）。
+// 标题带有 id 锚点，可在标题末尾用 {#锚点} 指定，{.term} 标记的标题可作为词条引用。
+// 与前端一样允许文档中的 HTML 原样输出
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// frontmatterRe 与 indexer 相同：文件开头 --- 包围的部分
+var frontmatterRe = regexp.MustCompile(`(?s)^---\r?\n(.+?)\r?\n---`)
+
+// Options 渲染选项
+type Options struct {
+	// Summary 只渲染 <!-- more --> 之前的内容
+	Summary bool
+}
+
+// Render 渲染文档（可带 frontmatter），返回 HTML 片段
+func Render(text string, opts Options) string {
+	p := &blockParser{refs: make(map[string]linkRef)}
+	blocks := p.parse(documentLines(text))
+
+	r := &renderer{refs: p.refs, ids: p.headingSlugs(blocks)}
+	for _, b := range blocks {
+		if b.kind == moreBlock && opts.Summary {
+			break
+		}
+		r.block(b, false)
+	}
+	return r.String()
+}
+
+// CodeLines 代码块（含 mermaid）和 HTML 块所占的行号（从 1 开始，计入 frontmatter），
+// 供 lint 等只处理正文的场景跳过，块的识别与渲染一致
+func CodeLines(text string) map[int]bool {
+	p := &blockParser{refs: make(map[string]linkRef)}
+	lines := make(map[int]bool)
+	var walk func(blocks []*block)
+	walk = func(blocks []*block) {
+		for _, b := range blocks {
+			switch b.kind {
+			case codeBlock, mermaidBlock, htmlBlock:
+				for n := b.line; n <= b.end; n++ {
+					lines[n] = true
+				}
+			case quoteBlock, listBlock, itemBlock:
+				walk(b.children)
+			}
+		}
+	}
+	walk(p.parse(documentLines(text)))
+	return lines
+}
+
+// documentLines 拆分为行，frontmatter 替换为空行以保持行号不变
+func documentLines(text string) []line {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if m := frontmatterRe.FindString(text); m != "" {
+		text = strings.Repeat("\n", strings.Count(m, "\n")) + text[len(m):]
+	}
+	var lines []line
+	for i, s := range strings.Split(text, "\n") {
+		lines = append(lines, line{text: expandTabs(s), num: i + 1})
+	}
+	return lines
+}
+
+// expandTabs 将行首的制表符展开为空格（制表位为 4）
+func expandTabs(s string) string {
+	if !strings.HasPrefix(s, "\t") && !strings.HasPrefix(s, " ") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\t':
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case ' ':
+			b.WriteByte(' ')
+			col++
+		default:
+			b.WriteString(s[i:])
+			return b.String()
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,190 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender_Blocks(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"段落", "第一行\n第二行", "<p data-line=\"1\">第一行\n第二行</p>\n"},
+		{"标题", "# 标题 #\n\n正文\n===", "<h1 id=\"标题\" data-line=\"1\">标题</h1>\n<h1 id=\"正文\" data-line=\"3\">正文</h1>\n"},
+		{"标题锚点", "## Hit Rules {#rules .term}\n## 判定 规则\n## 判定 规则\n> # 引用", "<h2 id=\"rules\" data-line=\"1\">Hit Rules</h2>\n<h2 id=\"判定-规则\" data-line=\"2\">判定 规则</h2>\n<h2 id=\"判定-规则-1\" data-line=\"3\">判定 规则</h2>\n<blockquote data-line=\"4\">\n<h1 data-line=\"4\">引用</h1>\n</blockquote>\n"},
+		{"分隔线", "a\n\n***", "<p data-line=\"1\">a</p>\n<hr data-line=\"3\">\n"},
+		{"代码块", "```go\nx := 1 < 2\n```", "<pre data-line=\"1\"><code class=\"language-go\">x := 1 &lt; 2\n</code></pre>\n"},
+		{"缩进代码", "    code\n\n    more", "<pre data-line=\"1\"><code>code\n\nmore\n</code></pre>\n"},
+		{"引用", "> 引用\n延续", "<blockquote data-line=\"1\">\n<p data-line=\"1\">引用\n延续</p>\n</blockquote>\n"},
+		{"紧凑列表", "- a\n- b\n  - c", "<ul>\n<li data-line=\"1\">a</li>\n<li data-line=\"2\">b\n<ul>\n<li data-line=\"3\">c</li>\n</ul>\n</li>\n</ul>\n"},
+		{"松散列表", "1. a\n\n2. b", "<ol>\n<li data-line=\"1\">\n<p data-line=\"1\">a</p>\n</li>\n<li data-line=\"3\">\n<p data-line=\"3\">b</p>\n</li>\n</ol>\n"},
+		{"起始序号", "3) a", "<ol start=\"3\">\n<li data-line=\"1\">a</li>\n</ol>\n"},
+		{"任务列表", "- [x] 完成\n- [ ] 待办", "<ul>\n<li class=\"task-list-item\" data-line=\"1\"><input class=\"task-list-item-checkbox\" type=\"checkbox\" disabled checked> 完成</li>\n<li class=\"task-list-item\" data-line=\"2\"><input class=\"task-list-item-checkbox\" type=\"checkbox\" disabled> 待办</li>\n</ul>\n"},
+		{"表格", "| 名称 | 数值 |\n|:--|--:|\n| 冲击 | 10 |", "<table data-line=\"1\">\n<thead>\n<tr>\n<th style=\"text-align:left\">名称</th>\n<th style=\"text-align:right\">数值</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td style=\"text-align:left\">冲击</td>\n<td style=\"text-align:right\">10</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"HTML", "<div>\n*原样*\n</div>", "<div>\n*原样*\n</div>\n"},
+		{"mermaid", "```mermaid\ngraph TD\n  A --> B\n```", "<pre class=\"mermaid\" data-line=\"1\">graph TD\n  A --&gt; B</pre>\n"},
+		{"more", "摘要\n<!-- more -->\n正文", "<p data-line=\"1\">摘要</p>\n<div class=\"wiki-more\" data-line=\"2\"></div>\n<p data-line=\"3\">正文</p>\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.src, Options{}); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRender_LongFence(t *testing.T) {
+	// 超过 1000 个字符的 fence 曾导致按长度构造的正则编译失败
+	fence := strings.Repeat("`", 1001)
+	src := fence + "\n" + strings.Repeat("`", 1000) + "\n   " + fence + "``  \n# 标题\n"
+	want := "<pre data-line=\"1\"><code>" + strings.Repeat("`", 1000) + "\n</code></pre>\n<h1 id=\"标题\" data-line=\"4\">标题</h1>\n"
+	if got := Render(src, Options{}); got != want {
+		t.Errorf("Render = %q", got)
+	}
+	if got := Headings(src); len(got) != 1 || got[0].Line != 4 {
+		t.Errorf("Headings = %+v", got)
+	}
+	Summarize(src, 0)
+}
+
+func TestRender_LongParagraph(t *testing.T) {
+	// 每次配对都在切片中查找并复制元素时，110KB 的段落需要十几秒
+	src := strings.Repeat("**粗体** ", 10000)
+	got := Render(src, Options{})
+	if n := strings.Count(got, "<strong>粗体</strong>"); n != 10000 {
+		t.Errorf("加粗数量 = %d", n)
+	}
+}
+
+func BenchmarkRender_LongParagraph(b *testing.B) {
+	src := strings.Repeat("**粗体** *强调 ~~删除~~ [链接](a.md) ", 2000)
+	for b.Loop() {
+		Render(src, Options{})
+	}
+}
+
+func TestRender_Inline(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"*强调* **加粗** ~~删除~~", "<em>强调</em> <strong>加粗</strong> <s>删除</s>"},
+		{"***两者***", "<em><strong>两者</strong></em>"},
+		{"snake_case_name", "snake_case_name"},
+		{"`a < b` \\*转义\\*", "<code>a &lt; b</code> *转义*"},
+		{"[链接](./战斗.md \"标题\") ![图](a.png)", "<a href=\"./战斗.md\" title=\"标题\">链接</a> <img src=\"a.png\" alt=\"图\">"},
+		{"[引用][r]\n\n[r]: https://example.com", "<a href=\"https://example.com\">引用</a>"},
+		{"见 https://example.com/a。", "见 <a href=\"https://example.com/a\">https://example.com/a</a>。"},
+		{"[x](javascript:alert(1))", "[x](javascript:alert(1))"},
+		{"a  \nb", "a<br>\nb"},
+		{"<span class=\"x\">原样</span> &copy; & <", "<span class=\"x\">原样</span> &copy; &amp; &lt;"},
+	}
+	for _, tt := range tests {
+		got := Render(tt.src, Options{})
+		got = strings.TrimSuffix(strings.TrimPrefix(got, `<p data-line="1">`), "</p>\n")
+		if got != tt.want {
+			t.Errorf("%q:\n got %q\nwant %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRender_WikiSyntax(t *testing.T) {
+	src := "---\nalias: 滑步\n---\n玩家【滑移】后产生【战斗/冲击】。\n【施法者】：发起滑移的玩家\n%% [伤害] = <攻击力> * 2 %%\n`【代码】`"
+	got := Render(src, Options{})
+	for _, want := range []string{
+		`<p data-line="4">`,
+		`<span class="wiki-term" data-term="滑移">【滑移】</span>`,
+		`<span class="wiki-term" data-term="战斗/冲击">【战斗/冲击】</span>`,
+		`<span class="wiki-definition" data-term="施法者" data-definition="发起滑移的玩家"><span class="wiki-definition__term">【施法者】</span>`,
+		`<span class="wiki-formula" data-formula="[伤害] = &lt;攻击力&gt; * 2"><span class="wiki-formula__calc" data-value="伤害">[伤害]</span> = <span class="wiki-formula__design" data-value="攻击力">&lt;攻击力&gt;</span> * 2</span>`,
+		`<code>【代码】</code>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("缺少 %s\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "alias") {
+		t.Errorf("frontmatter 不应输出: %s", got)
+	}
+}
+
+func TestRender_Summary(t *testing.T) {
+	got := Render("# 冲击\n\n摘要\n\n<!-- more -->\n\n细节", Options{Summary: true})
+	if strings.Contains(got, "细节") || !strings.Contains(got, "摘要") {
+		t.Errorf("摘要截断错误: %s", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	src := "---\nscope: 战斗\n---\n# 冲击\n\n冲击是**受击**后的硬直。\n【施法者】：发起者\n\n| 等级 | 时长 |\n|--|--|\n| 1 | 0.5s |\n\n```\n# 不是标题\n```\n\n<!-- more -->\n\n细节"
+	s := Summarize(src, 0)
+	wantMD := "冲击是**受击**后的硬直。\n\n| 等级 | 时长 |\n|--|--|\n| 1 | 0.5s |\n\n```\n# 不是标题\n```"
+	if s.Markdown != wantMD {
+		t.Errorf("Markdown:\n got %q\nwant %q", s.Markdown, wantMD)
+	}
+	if s.Text != "冲击是受击后的硬直。\n等级 | 时长\n1 | 0.5s\n# 不是标题" || !s.HasMore {
+		t.Errorf("Text = %q, HasMore = %v", s.Text, s.HasMore)
+	}
+
+	// 超过上限的块整体舍弃，纯文本截断
+	s = Summarize(src, 12)
+	if s.Markdown != "冲击是**受击**后的硬直。" || !s.HasMore {
+		t.Errorf("上限 12: %q %v", s.Markdown, s.HasMore)
+	}
+	s = Summarize("很长的第一段定义文字", 4)
+	if s.Markdown != "很长的第一段定义文字" || s.Text != "很长的第…" || !s.HasMore {
+		t.Errorf("上限 4: %+v", s)
+	}
+
+	if s := Summarize("只有正文", 0); s.HasMore || s.Text != "只有正文" {
+		t.Errorf("无 more: %+v", s)
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	src := "---\nscope: 战斗\n---\n# 冲击\n\n冲击是受击后的硬直。\n\n## 判定规则 {.term}\n\n命中时按**韧性**判定。\n\n### 例外\n\n霸体不受冲击。\n\n## Hit Rules {#hit}\n\n<!-- more -->\n"
+	got := Headings(src)
+	want := []Heading{
+		{Level: 1, Text: "冲击", Slug: "冲击", Line: 4, End: 18},
+		{Level: 2, Text: "判定规则", Slug: "判定规则", Line: 8, End: 14, Term: true},
+		{Level: 3, Text: "例外", Slug: "例外", Line: 12, End: 14},
+		{Level: 2, Text: "Hit Rules", Slug: "hit", Line: 16, End: 18},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Headings = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Headings[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	sections := Sections(src, 0)
+	if len(sections) != len(want) || sections[2].Heading != want[2] {
+		t.Fatalf("Sections = %+v", sections)
+	}
+	if s := sections[1].Summary; s.Markdown != "命中时按**韧性**判定。\n\n霸体不受冲击。" || s.Text != "命中时按韧性判定。\n霸体不受冲击。" || s.HasMore {
+		t.Errorf("Sections[1].Summary = %+v", s)
+	}
+	if s := sections[3].Summary; s.Markdown != "" || !s.HasMore {
+		t.Errorf("Sections[3].Summary（空章节）= %+v", s)
+	}
+	if got := Slug(" Hit  Rules: 冲击/受击！"); got != "hit--rules-冲击受击" {
+		t.Errorf("Slug = %q", got)
+	}
+}
+
+func TestSections_ManyHeadings(t *testing.T) {
+	// 每个章节都重新拆分、解析整篇文档时，上千个章节需要一秒以上
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		b.WriteString("## 章节\n\n正文\n\n")
+	}
+	sections := Sections(b.String(), 0)
+	if len(sections) != 2000 || sections[1999].End != 7999 || sections[1999].Summary.Markdown != "正文" {
+		t.Errorf("Sections = %d, last = %+v", len(sections), sections[len(sections)-1])
+	}
+
+	// setext 标题的下划线不属于章节内容
+	if got := Sections("标题\n===\n正文", 0); len(got) != 1 || got[0].Summary.Markdown != "正文" {
+		t.Errorf("setext: %+v", got)
+	}
+}
@@ -0,0 +1,232 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// formulaValueRe 公式中的计算值 [xxx] 和设计值 <xxx>
+var formulaValueRe = regexp.MustCompile(`\[([^\]]+)\]|<([^>]+)>`)
+
+type renderer struct {
+	strings.Builder
+	refs map[string]linkRef
+	ids  map[*block]string // 顶层标题的锚点
+}
+
+// block 输出块级元素；tight 为紧凑列表项中的内容，段落不加 <p>
+func (r *renderer) block(b *block, tight bool) {
+	switch b.kind {
+	case paragraphBlock:
+		if tight {
+			r.inline(b.text)
+			return
+		}
+		fmt.Fprintf(r, `<p data-line="%d">`, b.line)
+		r.inline(b.text)
+		r.WriteString("</p>\n")
+
+	case headingBlock:
+		r.WriteString("<h" + strconv.Itoa(b.level))
+		if id := r.ids[b]; id != "" {
+			r.WriteString(` id="` + escape(id) + `"`)
+		}
+		fmt.Fprintf(r, ` data-line="%d">`, b.line)
+		r.inline(b.text)
+		fmt.Fprintf(r, "</h%d>\n", b.level)
+
+	case codeBlock:
+		fmt.Fprintf(r, `<pre data-line="%d"><code`, b.line)
+		if lang, _, _ := strings.Cut(b.info, " "); lang != "" {
+			fmt.Fprintf(r, ` class="language-%s"`, escape(unescape(lang)))
+		}
+		r.WriteString(">" + escape(b.text) + "</code></pre>\n")
+
+	case mermaidBlock:
+		fmt.Fprintf(r, `<pre class="mermaid" data-line="%d">%s</pre>`+"\n", b.line, escape(strings.TrimSpace(b.text)))
+
+	case htmlBlock:
+		r.WriteString(b.text + "\n")
+
+	case moreBlock:
+		fmt.Fprintf(r, `<div class="wiki-more" data-line="%d"></div>`+"\n", b.line)
+
+	case quoteBlock:
+		fmt.Fprintf(r, `<blockquote data-line="%d">`+"\n", b.line)
+		for _, c := range b.children {
+			r.block(c, false)
+		}
+		r.WriteString("</blockquote>\n")
+
+	case listBlock:
+		tag := "ul"
+		if b.ordered {
+			tag = "ol"
+		}
+		r.WriteString("<" + tag)
+		if b.ordered && b.start != 1 {
+			r.WriteString(` start="` + strconv.Itoa(b.start) + `"`)
+		}
+		r.WriteString(">\n")
+		for _, item := range b.children {
+			r.item(item, b.tight)
+		}
+		r.WriteString("</" + tag + ">\n")
+
+	case tableBlock:
+		fmt.Fprintf(r, `<table data-line="%d">`+"\n<thead>\n", b.line)
+		r.row(b.rows[0], b.align, "th")
+		r.WriteString("</thead>\n")
+		if len(b.rows) > 1 {
+			r.WriteString("<tbody>\n")
+			for _, row := range b.rows[1:] {
+				r.row(row, b.align, "td")
+			}
+			r.WriteString("</tbody>\n")
+		}
+		r.WriteString("</table>\n")
+
+	case ruleBlock:
+		fmt.Fprintf(r, `<hr data-line="%d">`+"\n", b.line)
+	}
+}
+
+func (r *renderer) item(item *block, tight bool) {
+	r.WriteString("<li")
+	if item.task > 0 {
+		r.WriteString(` class="task-list-item"`)
+	}
+	fmt.Fprintf(r, ` data-line="%d">`, item.line)
+	if item.task > 0 {
+		r.WriteString(`<input class="task-list-item-checkbox" type="checkbox" disabled`)
+		if item.task == 2 {
+			r.WriteString(" checked")
+		}
+		r.WriteString("> ")
+	}
+	for i, c := range item.children {
+		inline := tight && c.kind == paragraphBlock
+		if !inline && (i == 0 || tight && item.children[i-1].kind == paragraphBlock) {
+			r.WriteString("\n")
+		}
+		r.block(c, tight)
+	}
+	r.WriteString("</li>\n")
+}
+
+func (r *renderer) row(cells, align []string, tag string) {
+	r.WriteString("<tr>\n")
+	for i, cell := range cells {
+		r.WriteString("<" + tag)
+		if align[i] != "" {
+			r.WriteString(` style="text-align:` + align[i] + `"`)
+		}
+		r.WriteString(">")
+		r.inline(cell)
+		r.WriteString("</" + tag + ">\n")
+	}
+	r.WriteString("</tr>\n")
+}
+
+func (r *renderer) inline(src string) {
+	r.nodes(parseInline(src, r.refs))
+}
+
+func (r *renderer) nodes(nodes []*node) {
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode:
+			r.WriteString(escape(n.text))
+		case rawNode:
+			r.WriteString(n.text)
+		case codeNode:
+			r.WriteString("<code>" + escape(n.text) + "</code>")
+		case softBreakNode:
+			r.WriteString("\n")
+		case hardBreakNode:
+			r.WriteString("<br>\n")
+		case emNode, strongNode, delNode:
+			tag := map[nodeKind]string{emNode: "em", strongNode: "strong", delNode: "s"}[n.kind]
+			r.WriteString("<" + tag + ">")
+			r.nodes(n.children)
+			r.WriteString("</" + tag + ">")
+		case linkNode:
+			r.WriteString(`<a href="` + escape(normalizeURL(n.text)) + `"`)
+			if n.title != "" {
+				r.WriteString(` title="` + escape(n.title) + `"`)
+			}
+			r.WriteString(">")
+			r.nodes(n.children)
+			r.WriteString("</a>")
+		case imageNode:
+			r.WriteString(`<img src="` + escape(normalizeURL(n.text)) + `" alt="` + escape(plainText(n.children)) + `"`)
+			if n.title != "" {
+				r.WriteString(` title="` + escape(n.title) + `"`)
+			}
+			r.WriteString(">")
+		case termNode:
+			r.WriteString(`<span class="wiki-term" data-term="` + escape(n.text) + `">【` + escape(n.text) + `】</span>`)
+		case definitionNode:
+			r.WriteString(`<span class="wiki-definition" data-term="` + escape(n.text) + `" data-definition="` + escape(n.title) + `">` +
+				`<span class="wiki-definition__term">【` + escape(n.text) + `】</span>` +
+				`<span class="wiki-definition__colon">：</span>` +
+				`<span class="wiki-definition__content">` + escape(n.title) + `</span></span>`)
+		case formulaNode:
+			r.formula(n.text)
+		}
+	}
+}
+
+// formula 公式中的计算值、设计值分别标出，便于按值查找定义
+func (r *renderer) formula(expr string) {
+	r.WriteString(`<span class="wiki-formula" data-formula="` + escape(expr) + `">`)
+	last := 0
+	for _, m := range formulaValueRe.FindAllStringSubmatchIndex(expr, -1) {
+		r.WriteString(escape(expr[last:m[0]]))
+		if m[2] >= 0 {
+			value := expr[m[2]:m[3]]
+			r.WriteString(`<span class="wiki-formula__calc" data-value="` + escape(value) + `">[` + escape(value) + `]</span>`)
+		} else {
+			value := expr[m[4]:m[5]]
+			r.WriteString(`<span class="wiki-formula__design" data-value="` + escape(value) + `">&lt;` + escape(value) + `&gt;</span>`)
+		}
+		last = m[1]
+	}
+	r.WriteString(escape(expr[last:]) + "</span>")
+}
+
+// plainText 行内内容的纯文本，用于图片的 alt 文字和定义摘要
+func plainText(nodes []*node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode, codeNode, formulaNode:
+			b.WriteString(n.text)
+		case rawNode:
+			if strings.HasPrefix(n.text, "&") {
+				b.WriteString(html.UnescapeString(n.text))
+			}
+		case softBreakNode, hardBreakNode:
+			b.WriteString(" ")
+		case termNode:
+			b.WriteString("【" + n.text + "】")
+		case definitionNode:
+			b.WriteString("【" + n.text + "】：" + n.title)
+		default:
+			b.WriteString(plainText(n.children))
+		}
+	}
+	return b.String()
+}
+
+// normalizeURL 编码地址中的空格
+func normalizeURL(url string) string {
+	return strings.ReplaceAll(url, " ", "%20")
+}
+
+func escape(s string) string {
+	return html.EscapeString(s)
+}
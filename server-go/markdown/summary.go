@@ -0,0 +1,122 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// inlineDefLineRe 文件内定义所在的行，与 indexer 相同，不计入文件定义
+	inlineDefLineRe = regexp.MustCompile(`【[^】]+】[：:]\s*\S`)
+	htmlTagStripRe  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Summary 文档的定义摘要
+type Summary struct {
+	// Markdown 摘要原文，由完整的块组成，不会截断表格、代码块
+	Markdown string
+	// Text 纯文本形式，超过字数上限时截断并以 … 结尾
+	Text string
+	// HasMore 文档在摘要之后还有内容（<!-- more --> 截断或超过字数上限）
+	HasMore bool
+}
+
+// Summarize 提取文档开头到 <!-- more --> 之间的内容作为定义，跳过标题、分隔线和文件内定义
+// limit 大于 0 时为纯文本的字数上限：超过上限的块不再加入（至少保留第一个块）
+func Summarize(text string, limit int) Summary {
+	p := &blockParser{refs: make(map[string]linkRef)}
+	lines := documentLines(text)
+	return p.summarize(lines, p.parse(lines), len(lines), limit)
+}
+
+// summarize 由 blocks 中第 end 行之前的块生成摘要，lines 为整篇文档的行
+func (p *blockParser) summarize(lines []line, blocks []*block, end, limit int) Summary {
+	var s Summary
+	var sources, texts []string
+	count := 0
+	for _, b := range blocks {
+		if b.line > end {
+			break
+		}
+		if b.kind == moreBlock {
+			s.HasMore = true
+			break
+		}
+		if b.kind == headingBlock || b.kind == ruleBlock {
+			continue
+		}
+
+		var source []string
+		for _, l := range lines[b.line-lines[0].num : b.end-lines[0].num+1] {
+			if b.kind == paragraphBlock && inlineDefLineRe.MatchString(l.text) {
+				continue
+			}
+			source = append(source, l.text)
+		}
+		if len(source) == 0 {
+			continue
+		}
+		plain := p.plain(b)
+		if b.kind == paragraphBlock {
+			plain = p.plain(&block{kind: paragraphBlock, text: strings.TrimSpace(strings.Join(source, "\n"))})
+		}
+
+		n := utf8.RuneCountInString(plain)
+		if limit > 0 && len(sources) > 0 && count+n > limit {
+			s.HasMore = true
+			break
+		}
+		sources = append(sources, strings.Join(source, "\n"))
+		if plain != "" {
+			texts = append(texts, plain)
+		}
+		count += n
+	}
+
+	s.Markdown = strings.Join(sources, "\n\n")
+	s.Text = strings.Join(texts, "\n")
+	if limit > 0 && utf8.RuneCountInString(s.Text) > limit {
+		s.Text = string([]rune(s.Text)[:limit]) + "…"
+		s.HasMore = true
+	}
+	return s
+}
+
+// plain 块的纯文本
+func (p *blockParser) plain(b *block) string {
+	switch b.kind {
+	case paragraphBlock, headingBlock:
+		return strings.TrimSpace(plainText(parseInline(b.text, p.refs)))
+	case codeBlock:
+		return strings.TrimRight(b.text, "\n")
+	case htmlBlock:
+		return strings.TrimSpace(html.UnescapeString(htmlTagStripRe.ReplaceAllString(b.text, "")))
+	case quoteBlock, itemBlock:
+		var parts []string
+		for _, c := range b.children {
+			if t := p.plain(c); t != "" {
+				parts = append(parts, t)
+			}
+		}
+		return strings.Join(parts, "\n")
+	case listBlock:
+		var items []string
+		for _, item := range b.children {
+			items = append(items, strings.ReplaceAll(p.plain(item), "\n", " "))
+		}
+		return strings.Join(items, "\n")
+	case tableBlock:
+		var rows []string
+		for _, row := range b.rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = strings.TrimSpace(plainText(parseInline(cell, p.refs)))
+			}
+			rows = append(rows, strings.Join(cells, " | "))
+		}
+		return strings.Join(rows, "\n")
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// BundleDataID 单文件导出中内嵌 data/ 文件的 <script type="application/json"> 的 id
+const BundleDataID = "xlxz-wiki-data"
+
+var (
+	scriptTag = regexp.MustCompile(`<script\b[^>]*\bsrc="([^"]+)"[^>]*>\s*</script>`)
+	linkTag   = regexp.MustCompile(`<link\b[^>]*\brel="(stylesheet|modulepreload)"[^>]*>`)
+	hrefAttr  = regexp.MustCompile(`\bhref="([^"]+)"`)
+	cssURL    = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+)
+
+// ExportBundle 将工作区导出为单个 HTML 文件，双击即可离线浏览：
+// 脚本和样式内联，样式引用的字体、图片转为 data URL，
+// ExportStatic 中 data/ 下的文件以 JSON 内嵌在页面中，由前端代替 fetch 读取。
+// 要求前端脚本打包为单个文件（file:// 下无法加载拆分的 chunk）
+func (s *Server) ExportBundle(w io.Writer, workspace string) error {
+	space, err := s.workspaceNamed(workspace)
+	if err != nil {
+		return err
+	}
+	site, err := s.snapshot(space)
+	if err != nil {
+		return err
+	}
+	html, err := inlineAssets(site)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]string)
+	for name, content := range site {
+		if strings.HasPrefix(name, "data/") {
+			data[name] = string(content)
+		}
+	}
+	// json.Marshal 会转义 <，内容中的 </script> 不会提前结束标签
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	tag := `<script type="application/json" id="` + BundleDataID + `">` + string(payload) + "</script>\n"
+	if i := strings.Index(html, "</head>"); i >= 0 {
+		html = html[:i] + tag + html[i:]
+	} else {
+		html = tag + html
+	}
+
+	_, err = io.WriteString(w, html)
+	return err
+}
+
+// inlineAssets 把 index.html 引用的脚本和样式替换为内联内容
+func inlineAssets(site map[string][]byte) (string, error) {
+	html := string(site["index.html"])
+	inlined := make(map[string]bool)
+	var missing error
+
+	read := func(ref string) []byte {
+		name := path.Clean(strings.TrimPrefix(ref, "/"))
+		content, ok := site[name]
+		if !ok && missing == nil {
+			missing = fmt.Errorf("index.html 引用的 %s 不存在", ref)
+		}
+		inlined[name] = true
+		return content
+	}
+
+	html = scriptTag.ReplaceAllStringFunc(html, func(tag string) string {
+		code := read(scriptTag.FindStringSubmatch(tag)[1])
+		return "<script type=\"module\">\n" + escapeClosingTag(string(code), "script") + "\n</script>"
+	})
+	html = linkTag.ReplaceAllStringFunc(html, func(tag string) string {
+		href := hrefAttr.FindStringSubmatch(tag)
+		if href == nil {
+			return tag
+		}
+		if linkTag.FindStringSubmatch(tag)[1] == "modulepreload" {
+			// 预加载的模块已经在内联脚本中
+			read(href[1])
+			return ""
+		}
+		name := path.Clean(strings.TrimPrefix(href[1], "/"))
+		css := inlineCSSURLs(string(read(href[1])), path.Dir(name), site)
+		return "<style>\n" + escapeClosingTag(css, "style") + "\n</style>"
+	})
+	if missing != nil {
+		return "", missing
+	}
+
+	for name := range site {
+		if strings.HasSuffix(name, ".js") && !inlined[name] {
+			return "", fmt.Errorf("前端脚本被拆分为多个文件（%s），无法内联为单个页面", name)
+		}
+	}
+	return html, nil
+}
+
+// inlineCSSURLs 把样式中引用的本地文件替换为 data URL，dir 为样式文件所在目录
+func inlineCSSURLs(css, dir string, site map[string][]byte) string {
+	return cssURL.ReplaceAllStringFunc(css, func(m string) string {
+		ref := cssURL.FindStringSubmatch(m)[1]
+		if strings.HasPrefix(ref, "data:") || strings.Contains(ref, "://") {
+			return m
+		}
+		ref, _, _ = strings.Cut(ref, "?")
+		ref, _, _ = strings.Cut(ref, "#")
+		name := path.Join(dir, ref)
+		if strings.HasPrefix(ref, "/") {
+			name = path.Clean(ref[1:])
+		}
+		content, ok := site[name]
+		if !ok {
+			return m
+		}
+		mime, _, _ := strings.Cut(getMimeType(name), ";")
+		return `url("data:` + mime + ";base64," + base64.StdEncoding.EncodeToString(content) + `")`
+	})
+}
+
+// escapeClosingTag 避免内联内容中的 </script> 等提前结束标签
+func escapeClosingTag(s, tag string) string {
+	return strings.ReplaceAll(s, "</"+tag, `<\/`+tag)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func bundleAssets() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html": {Data: []byte(`<html><head>
+<script type="module" crossorigin src="./assets/index.js"></script>
+<link rel="modulepreload" crossorigin href="./assets/index.js">
+<link rel="stylesheet" crossorigin href="./assets/index.css">
+</head><body><div id="app"></div></body></html>`)},
+		"assets/index.js":   {Data: []byte(`document.write("</script>")`)},
+		"assets/index.css":  {Data: []byte(`@font-face{src:url(./font.woff2)}body{background:url("data:image/png;base64,AA")}`)},
+		"assets/font.woff2": {Data: []byte("font")},
+	}
+}
+
+func TestExportBundle(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"滑移.md":    "滑移的定义 </script>\n",
+		"战斗/冲击.md": "冲击的定义\n",
+	})
+	c.Assets = bundleAssets()
+	s := newTestServer(t, c)
+
+	var buf bytes.Buffer
+	if err := s.ExportBundle(&buf, ""); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		StaticMeta,
+		"<script type=\"module\">\ndocument.write(\"<\\/script>\")\n</script>",
+		`url("data:font/woff2;base64,Zm9udA==")`,
+		`url("data:image/png;base64,AA")`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("缺少 %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "assets/") {
+		t.Errorf("仍引用外部文件:\n%s", html)
+	}
+
+	m := regexp.MustCompile(`<script type="application/json" id="` + BundleDataID + `">(.*)</script>`).FindStringSubmatch(html)
+	if m == nil {
+		t.Fatal("缺少内嵌数据")
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(m[1]), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["data/docs/滑移.md"] != "滑移的定义 </script>\n" || data["data/docs/战斗/冲击.md"] == "" {
+		t.Errorf("文档不完整: %v", data)
+	}
+	for _, name := range []string{"data/index.json", "data/files.json", "data/version.json", "data/workspaces.json"} {
+		if data[name] == "" {
+			t.Errorf("缺少 %s", name)
+		}
+	}
+}
+
+func TestExportBundle_SplitChunks(t *testing.T) {
+	c := testConfig(t, nil)
+	assets := bundleAssets()
+	assets["assets/DocView.js"] = &fstest.MapFile{Data: []byte("export default {}")}
+	c.Assets = assets
+	s := newTestServer(t, c)
+	if err := s.ExportBundle(&bytes.Buffer{}, ""); err == nil {
+		t.Error("脚本拆分为多个文件时应返回错误")
+	}
+}
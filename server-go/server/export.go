@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"xlxz-wiki/auth"
+)
+
+// StaticMeta 静态导出的 index.html 中的标记，前端据此从 data/ 读取数据、使用 hash 路由
+const StaticMeta = `<meta name="xlxz-wiki-static" content="1">`
+
+// ExportStatic 将工作区导出为静态站点目录，不需要 Go 服务器即可浏览：
+//
+//	index.html, assets/     前端页面
+//	data/index.json         词条和公式索引（悬停卡片、公式，含其他工作区的词条）
+//	data/files.json         文件树
+//	data/tags.json          标签及其文档
+//	data/version.json       版本和只读标记
+//	data/workspaces.json    工作区列表（只有导出的工作区）
+//	data/docs/<路径>.md     文档原文
+//
+// 数据与服务器 API 的响应相同；workspace 为空时导出默认工作区。
+// dir 中已有的导出内容会被替换，其他文件保留
+func (s *Server) ExportStatic(dir, workspace string) error {
+	space, err := s.workspaceNamed(workspace)
+	if err != nil {
+		return err
+	}
+	site, err := s.snapshot(space)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"index.html", "assets", "data"} {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	for name, content := range site {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspaceNamed 按名称查找工作区，名称为空时为默认工作区（导出、检查等命令共用）
+func (s *Server) workspaceNamed(name string) (*workspace, error) {
+	if name == "" {
+		return s.workspaces[0], nil
+	}
+	space := s.byName[name]
+	if space == nil {
+		return nil, fmt.Errorf("未知工作区: %s", name)
+	}
+	return space, nil
+}
+
+// snapshot 生成静态站点的全部文件：站点内路径 → 内容
+func (s *Server) snapshot(space *workspace) (map[string][]byte, error) {
+	if s.cfg.Assets == nil {
+		return nil, errors.New("前端资源未构建，请先运行 pnpm build")
+	}
+	site := make(map[string][]byte)
+
+	err := fs.WalkDir(s.cfg.Assets, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(s.cfg.Assets, path)
+		if err != nil {
+			return err
+		}
+		if path == "index.html" {
+			content = bytes.Replace(content, []byte("<head>"), []byte("<head>\n    "+StaticMeta), 1)
+		}
+		site[path] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取前端资源: %w", err)
+	}
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"data/index.json": s.handleIndex,
+		"data/files.json": s.handleFiles,
+		"data/tags.json":  s.handleTags,
+	} {
+		if site[name], err = s.call(space, handler, "/"); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if site["data/version.json"], err = json.Marshal(map[string]any{
+		"version":  s.cfg.Version,
+		"user":     auth.User{Role: auth.Viewer},
+		"readOnly": true,
+	}); err != nil {
+		return nil, err
+	}
+	if site["data/workspaces.json"], err = json.Marshal(map[string]any{
+		"current":    space.name,
+		"workspaces": []map[string]any{{"name": space.name, "url": "./"}},
+	}); err != nil {
+		return nil, err
+	}
+
+	var walk func(nodes []*FileTreeNode) error
+	walk = func(nodes []*FileTreeNode) error {
+		for _, node := range nodes {
+			paths := []string{node.ReadmePath}
+			if !node.IsDirectory {
+				paths[0] = node.Path
+			}
+			for _, path := range paths {
+				if path == "" {
+					continue
+				}
+				content, err := os.ReadFile(filepath.Join(space.docsDir, filepath.FromSlash(path)))
+				if err != nil {
+					return err
+				}
+				site["data/docs/"+path] = content
+			}
+			if err := walk(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(buildFileTree(space.docsDir, "", s.ignore)); err != nil {
+		return nil, fmt.Errorf("读取文档: %w", err)
+	}
+	return site, nil
+}
+
+// call 在进程内调用处理函数（不经过访问控制），返回响应内容
+func (s *Server) call(space *workspace, h http.HandlerFunc, target string) ([]byte, error) {
+	r := httptest.NewRequest("GET", target, nil)
+	r = r.WithContext(context.WithValue(r.Context(), routeKey{}, route{root: "/", base: "/", space: space}))
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusOK {
+		return nil, fmt.Errorf("%d %s", rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+	return rec.Body.Bytes(), nil
+}
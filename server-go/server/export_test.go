@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExportStatic(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"滑移.md":        "---\nalias:\n  - 滑步\n---\n滑移的定义\n",
+		"战斗/README.md": "战斗系统\n",
+		"战斗/冲击.md":     "%% [伤害] = <攻击力> * 2 %%\n",
+		"drafts/草稿.md": "不导出\n",
+	})
+	c.Ignore = []string{"drafts/**"}
+	c.Assets = fstest.MapFS{
+		"index.html":    {Data: []byte("<html><head><title>wiki</title></head></html>")},
+		"assets/app.js": {Data: []byte("console.log(1)")},
+	}
+	s := newTestServer(t, c)
+
+	out := t.TempDir()
+	os.WriteFile(filepath.Join(out, "keep.txt"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(out, "data", "docs"), 0755)
+	os.WriteFile(filepath.Join(out, "data", "docs", "旧.md"), []byte("x"), 0644)
+	if err := s.ExportStatic(out, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if html := read("index.html"); !strings.Contains(html, StaticMeta) {
+		t.Errorf("index.html 缺少静态标记: %s", html)
+	}
+	if read("assets/app.js") != "console.log(1)" {
+		t.Error("前端资源未复制")
+	}
+	if read("data/docs/战斗/冲击.md") != "%% [伤害] = <攻击力> * 2 %%\n" {
+		t.Error("文档原文不一致")
+	}
+	read("data/docs/战斗/README.md")
+	read("keep.txt")
+	for _, name := range []string{"data/docs/drafts/草稿.md", "data/docs/旧.md"} {
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(name))); err == nil {
+			t.Errorf("不应存在 %s", name)
+		}
+	}
+
+	var index struct {
+		Terms    map[string]json.RawMessage `json:"terms"`
+		Formulas map[string]json.RawMessage `json:"formulas"`
+	}
+	if err := json.Unmarshal([]byte(read("data/index.json")), &index); err != nil {
+		t.Fatal(err)
+	}
+	if index.Terms["滑步"] == nil || index.Formulas["伤害"] == nil {
+		t.Errorf("索引不完整: %v %v", len(index.Terms), len(index.Formulas))
+	}
+
+	var version struct {
+		ReadOnly bool `json:"readOnly"`
+		User     struct {
+			Role string `json:"role"`
+		} `json:"user"`
+	}
+	json.Unmarshal([]byte(read("data/version.json")), &version)
+	if !version.ReadOnly || version.User.Role != "viewer" {
+		t.Errorf("version.json = %+v", version)
+	}
+	if !strings.Contains(read("data/files.json"), "冲击.md") {
+		t.Error("文件树缺少文档")
+	}
+}
+
+func TestExportStatic_Errors(t *testing.T) {
+	c := testConfig(t, nil)
+	s := newTestServer(t, c)
+	if err := s.ExportStatic(t.TempDir(), ""); err == nil {
+		t.Error("没有前端资源时应返回错误")
+	}
+	c.Assets = fstest.MapFS{"index.html": {Data: []byte("<head></head>")}}
+	s = newTestServer(t, c)
+	if err := s.ExportStatic(t.TempDir(), "不存在"); err == nil {
+		t.Error("未知工作区应返回错误")
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"xlxz-wiki/ignore"
+)
+
+// FileTreeNode 文件树节点
+type FileTreeNode struct {
+	Name        string          `json:"name"`
+	Path        string          `json:"path"`
+	IsDirectory bool            `json:"isDirectory"`
+	Children    []*FileTreeNode `json:"children,omitempty"`
+	ReadmePath  string          `json:"readmePath,omitempty"`
+	// Tags 文档的标签；目录为同名 .md 文件的标签
+	Tags []string `json:"tags,omitempty"`
+}
+
+// buildFileTree 构建 relativePath 下的文件树，跳过隐藏文件和被忽略的路径
+func buildFileTree(rootDir, relativePath string, ignored *ignore.Matcher) []*FileTreeNode {
+	fullPath := filepath.Join(rootDir, relativePath)
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return []*FileTreeNode{}
+	}
+
+	nodes := make([]*FileTreeNode, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		nodePath := filepath.Join(relativePath, name)
+		if strings.HasPrefix(name, ".") || ignored.Match(nodePath) {
+			continue
+		}
+
+		node := &FileTreeNode{
+			Name:        name,
+			Path:        filepath.ToSlash(nodePath),
+			IsDirectory: entry.IsDir(),
+		}
+
+		if entry.IsDir() {
+			node.Children = buildFileTree(rootDir, nodePath, ignored)
+		} else if !strings.HasSuffix(name, ".md") {
+			continue // 只显示 .md 文件
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	// 合并同名目录和 .md 文件
+	dirMap := make(map[string]*FileTreeNode)
+	for _, node := range nodes {
+		if node.IsDirectory {
+			dirMap[node.Name] = node
+		}
+	}
+
+	filteredNodes := make([]*FileTreeNode, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.IsDirectory && strings.HasSuffix(node.Name, ".md") {
+			dirName := strings.TrimSuffix(node.Name, ".md")
+			if dirNode, exists := dirMap[dirName]; exists {
+				dirNode.ReadmePath = node.Path
+				continue // 跳过该 .md 文件节点，不加入最终列表
+			}
+		}
+		filteredNodes = append(filteredNodes, node)
+	}
+
+	return filteredNodes
+}
+
+// setTags 为文件和带同名 .md 的目录填上索引中的标签
+func setTags(nodes []*FileTreeNode, tags func(relPath string) []string) {
+	for _, node := range nodes {
+		switch {
+		case !node.IsDirectory:
+			node.Tags = tags(node.Path)
+		case node.ReadmePath != "":
+			node.Tags = tags(node.ReadmePath)
+		}
+		setTags(node.Children, tags)
+	}
+}
+
+// filterTree 只保留带有全部 want 标签的文档，以及包含这些文档的目录
+func filterTree(nodes []*FileTreeNode, want []string) []*FileTreeNode {
+	filtered := make([]*FileTreeNode, 0)
+	for _, node := range nodes {
+		matched := len(node.Tags) > 0 && containsAll(node.Tags, want)
+		if node.IsDirectory {
+			node.Children = filterTree(node.Children, want)
+			matched = matched || len(node.Children) > 0
+		}
+		if matched {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
+func containsAll(have, want []string) bool {
+	for _, tag := range want {
+		if !slices.Contains(have, tag) {
+			return false
+		}
+	}
+	return true
+}
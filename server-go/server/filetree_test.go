@@ -1,4 +1,4 @@
-package main
+package server
 
 import (
 	"encoding/json"
@@ -34,7 +34,7 @@ func TestBuildFileTree_MergesSameNameDirAndFile(t *testing.T) {
 	}
 
 	// 调用 buildFileTree
-	tree := buildFileTree(tmpDir, "")
+	tree := buildFileTree(tmpDir, "", nil)
 
 	// 打印结果用于调试
 	jsonBytes, _ := json.MarshalIndent(tree, "", "  ")
@@ -84,7 +84,7 @@ func TestBuildFileTree_NoMergeWhenOnlyFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tree := buildFileTree(tmpDir, "")
+	tree := buildFileTree(tmpDir, "", nil)
 
 	if len(tree) != 1 {
 		t.Errorf("期望 1 个节点，实际 %d 个", len(tree))
@@ -104,7 +104,7 @@ func TestBuildFileTree_NoMergeWhenOnlyDir(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tree := buildFileTree(tmpDir, "")
+	tree := buildFileTree(tmpDir, "", nil)
 
 	if len(tree) != 1 {
 		t.Errorf("期望 1 个节点，实际 %d 个", len(tree))
@@ -139,7 +139,7 @@ func TestBuildFileTree_NestedMerge(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tree := buildFileTree(tmpDir, "")
+	tree := buildFileTree(tmpDir, "", nil)
 
 	// 打印结果
 	jsonBytes, _ := json.MarshalIndent(tree, "", "  ")
@@ -0,0 +1,519 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"xlxz-wiki/annotation"
+	"xlxz-wiki/auth"
+	"xlxz-wiki/indexer"
+	"xlxz-wiki/markdown"
+)
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	w.Header().Set("Content-Type", "application/json")
+	index := space.idx.IndexWithSources()
+	// exclude=draft&exclude=deprecated 隐藏这些状态的文档中的定义
+	if statuses := r.URL.Query()["exclude"]; len(statuses) > 0 {
+		index = index.WithoutStatus(statuses...)
+	}
+	json.NewEncoder(w).Encode(index)
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "缺少 path 参数", 400)
+		return
+	}
+
+	fullPath, ok := space.resolveDocPath(path)
+	if !ok {
+		http.Error(w, "非法路径", 403)
+		return
+	}
+
+	if r.Method == "POST" {
+		// 写入文件
+		var body struct {
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "无效的请求体", 400)
+			return
+		}
+		if err := space.writeDocument(fullPath, body.Content); err != nil {
+			http.Error(w, "写入失败: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	} else {
+		// 读取文件
+		content, err := os.ReadFile(fullPath)
+		if err != nil {
+			http.Error(w, "文件不存在", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(content)
+	}
+}
+
+// handleFileStatus 文档状态：GET 返回当前状态和可以变更到的状态，POST 按流转规则写入 frontmatter
+func (s *Server) handleFileStatus(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "缺少 path 参数", 400)
+		return
+	}
+	fullPath, ok := space.resolveDocPath(path)
+	if !ok {
+		http.Error(w, "非法路径", 403)
+		return
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		http.Error(w, "文件不存在", 404)
+		return
+	}
+	status := indexer.ParseStatus(string(content))
+
+	if r.Method == "POST" {
+		var body struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "无效的请求体", 400)
+			return
+		}
+		if !indexer.ValidStatus(body.Status) {
+			http.Error(w, "未知状态: "+body.Status, 400)
+			return
+		}
+		if !indexer.CanTransition(status, body.Status) {
+			http.Error(w, fmt.Sprintf("不能从 %q 变更为 %q", status, body.Status), 409)
+			return
+		}
+		if err := space.rewriteDocument(fullPath, string(content), indexer.SetFrontmatter(string(content), "status", body.Status)); err != nil {
+			writeFailed(w, err)
+			return
+		}
+		user := auth.FromRequest(r).Name
+		if user == "" {
+			user, _, _ = net.SplitHostPort(r.RemoteAddr)
+		}
+		log.Printf("[状态] %s 将 %s 从 %q 变更为 %q", user, path, status, body.Status)
+		status = body.Status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"path": path, "status": status, "next": indexer.NextStatuses(status)})
+}
+
+// handleRender 在服务端将文档渲染为 HTML 片段，供不经过前端的客户端使用
+// summary=1 时只渲染 <!-- more --> 之前的部分
+func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "缺少 path 参数", 400)
+		return
+	}
+	fullPath, ok := space.resolveDocPath(path)
+	if !ok {
+		http.Error(w, "非法路径", 403)
+		return
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		http.Error(w, "文件不存在", 404)
+		return
+	}
+	opts := markdown.Options{Summary: r.URL.Query().Get("summary") == "1"}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, markdown.Render(string(content), opts))
+}
+
+// handleOutline 返回文档的标题树，大纲随索引更新，不需要重新读取文件
+func (s *Server) handleOutline(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "缺少 path 参数", 400)
+		return
+	}
+	fullPath, ok := space.resolveDocPath(path)
+	if !ok {
+		http.Error(w, "非法路径", 403)
+		return
+	}
+	relPath, _ := filepath.Rel(space.docsDir, fullPath)
+	outline, ok := space.idx.Outline(filepath.ToSlash(relPath))
+	if !ok {
+		http.Error(w, "文件不存在", 404)
+		return
+	}
+	if outline == nil {
+		outline = []*indexer.OutlineItem{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(outline)
+}
+
+// resolveDocPath 将文档相对路径转为完整路径，拒绝跳出文档目录的路径
+func (space *workspace) resolveDocPath(path string) (string, bool) {
+	fullPath := filepath.Join(space.docsDir, path)
+	// 安全检查：防止路径遍历（包括进入名称前缀相同的相邻目录，如 wiki 与 wiki-b）
+	rel, err := filepath.Rel(space.docsDir, fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return fullPath, true
+}
+
+// errDocumentChanged 读取之后文档又被修改（其他请求保存或外部编辑），放弃本次改写
+var errDocumentChanged = errors.New("文档已被修改，请刷新后重试")
+
+// fileLocks 按完整路径串行化文档和批注文件的写入
+type fileLocks struct {
+	mu    sync.Mutex
+	paths map[string]*sync.Mutex
+}
+
+// lock 锁定 path，返回解锁函数
+func (l *fileLocks) lock(path string) func() {
+	l.mu.Lock()
+	if l.paths == nil {
+		l.paths = make(map[string]*sync.Mutex)
+	}
+	m, ok := l.paths[path]
+	if !ok {
+		m = &sync.Mutex{}
+		l.paths[path] = m
+	}
+	l.mu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// writeDocument 写入编辑保存的文档内容
+func (space *workspace) writeDocument(fullPath, content string) error {
+	defer space.locks.lock(fullPath)()
+	return os.WriteFile(fullPath, []byte(content), 0644)
+}
+
+// rewriteDocument 将读取时内容为 old 的文档改写为 content（采纳建议、修改状态、lint 修复共用），
+// 写入前在锁内重新读取比对，期间文档被修改时返回 errDocumentChanged
+func (space *workspace) rewriteDocument(fullPath, old, content string) error {
+	defer space.locks.lock(fullPath)()
+	current, err := os.ReadFile(fullPath)
+	if err != nil {
+		return err
+	}
+	if string(current) != old {
+		return errDocumentChanged
+	}
+	return os.WriteFile(fullPath, []byte(content), 0644)
+}
+
+// writeFailed 写入失败的响应：文档已被修改时返回 409
+func writeFailed(w http.ResponseWriter, err error) {
+	status := 500
+	if errors.Is(err, errDocumentChanged) {
+		status = 409
+	}
+	http.Error(w, "写入失败: "+err.Error(), status)
+}
+
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	tree := buildFileTree(space.docsDir, "", s.ignore)
+	setTags(tree, space.idx.FileTags)
+	if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+		tree = filterTree(tree, tags)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	q := r.URL.Query().Get("q")
+	results := space.idx.Search(q, r.URL.Query()["tag"]...)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// handleTags 所有标签及带有该标签的文档
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(space.idx.Tags())
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"version":  s.cfg.Version,
+		"user":     auth.FromRequest(r),
+		"readOnly": s.cfg.ReadOnly,
+		"autoLink": s.cfg.Features.AutoLink,
+	})
+}
+
+// handleStatus 服务运行状态：索引规模、文件监听与定期对账的统计
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"version":   s.cfg.Version,
+		"readOnly":  s.cfg.ReadOnly,
+		"workspace": space.name,
+		"buildTime": space.idx.BuildTime(),
+		"termCount": space.idx.TermCount(),
+		"fileCount": space.idx.FileCount(),
+		"watcher":   space.watcher.Stats(),
+	})
+}
+
+// handleWorkspaces 列出工作区及其访问路径，current 为请求所属的工作区
+func (s *Server) handleWorkspaces(w http.ResponseWriter, r *http.Request) {
+	rt := r.Context().Value(routeKey{}).(route)
+
+	type workspaceInfo struct {
+		Name      string `json:"name"`
+		URL       string `json:"url"`
+		FileCount int    `json:"fileCount"`
+		TermCount int    `json:"termCount"`
+	}
+	list := make([]workspaceInfo, 0, len(s.workspaces))
+	for i, space := range s.workspaces {
+		url := rt.root
+		if i > 0 {
+			url = workspaceBase(rt.root, space)
+		}
+		list = append(list, workspaceInfo{
+			Name:      space.name,
+			URL:       url,
+			FileCount: space.idx.FileCount(),
+			TermCount: space.idx.TermCount(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"current":    rt.space.name,
+		"workspaces": list,
+	})
+}
+
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "缺少 path 参数", 400)
+		return
+	}
+
+	// 批注存储目录：wiki-docs/.annotations/
+	annotationsDir := filepath.Join(space.docsDir, annotation.DirName)
+	annotationPath := filepath.Join(annotationsDir, annotation.FileName(path))
+
+	// 安全检查
+	if !strings.HasPrefix(annotationPath, annotationsDir) {
+		http.Error(w, "非法路径", 403)
+		return
+	}
+
+	if r.Method == "POST" {
+		// 确保目录存在
+		if err := os.MkdirAll(annotationsDir, 0755); err != nil {
+			http.Error(w, "创建目录失败: "+err.Error(), 500)
+			return
+		}
+
+		var file annotation.File
+		if err := json.NewDecoder(r.Body).Decode(&file); err != nil {
+			http.Error(w, "无效的请求体", 400)
+			return
+		}
+
+		// 与采纳建议串行，并保留已采纳批注的状态
+		defer space.locks.lock(annotationPath)()
+		if content, err := os.ReadFile(annotationPath); err == nil {
+			var stored annotation.File
+			if json.Unmarshal(content, &stored) == nil {
+				file.KeepApplied(&stored)
+			}
+		}
+
+		// 格式化 JSON（便于人类阅读和 agent 解析）
+		formatted, _ := json.MarshalIndent(&file, "", "  ")
+		if err := os.WriteFile(annotationPath, formatted, 0644); err != nil {
+			http.Error(w, "写入失败: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	} else {
+		// 读取批注文件
+		content, err := os.ReadFile(annotationPath)
+		if err != nil {
+			http.Error(w, "批注不存在", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(content)
+	}
+}
+
+func (s *Server) handleAnnotationsExport(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	format := r.URL.Query().Get("format")
+	var statuses []string
+	if s := r.URL.Query().Get("status"); s != "" {
+		statuses = strings.Split(s, ",")
+	}
+
+	report, err := annotation.BuildReport(space.docsDir, statuses)
+	if err != nil {
+		http.Error(w, "读取批注失败: "+err.Error(), 500)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, format); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	w.Header().Set("Content-Type", annotation.ContentType(format))
+	w.Write(buf.Bytes())
+}
+
+// handleAnnotationApply 采纳批注中的建议修改：替换原文、写回文档并将批注标记为已解决
+func (s *Server) handleAnnotationApply(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	if r.Method != "POST" {
+		http.Error(w, "仅支持 POST", 405)
+		return
+	}
+	path := r.URL.Query().Get("path")
+	id := r.URL.Query().Get("id")
+	if path == "" || id == "" {
+		http.Error(w, "缺少 path 或 id 参数", 400)
+		return
+	}
+
+	fullPath, ok := space.resolveDocPath(path)
+	if !ok {
+		http.Error(w, "非法路径", 403)
+		return
+	}
+
+	// 登录用户以账号名记录，匿名访问时使用客户端提供的名字
+	var body struct {
+		AppliedBy string `json:"appliedBy"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	appliedBy := auth.FromRequest(r).Name
+	if appliedBy == "" {
+		appliedBy = strings.TrimSpace(body.AppliedBy)
+	}
+	if appliedBy == "" {
+		appliedBy, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+
+	// 批注文件按规范化的文档路径读写，不使用文件中记录的 filePath
+	relPath, _ := filepath.Rel(space.docsDir, fullPath)
+	relPath = filepath.ToSlash(relPath)
+	// 读取到写回批注期间锁定批注文件，避免与保存批注列表的请求交错
+	defer space.locks.lock(annotation.Path(space.docsDir, relPath))()
+	file, err := annotation.Load(space.docsDir, relPath)
+	if err != nil {
+		http.Error(w, "批注不存在", 404)
+		return
+	}
+	file.FilePath = relPath
+	a := file.Find(id)
+	if a == nil {
+		http.Error(w, "批注不存在", 404)
+		return
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		http.Error(w, "文件不存在", 404)
+		return
+	}
+	updated, err := a.ApplySuggestion(string(content))
+	if err != nil {
+		status := 409
+		if errors.Is(err, annotation.ErrNoSuggestion) {
+			status = 400
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := space.rewriteDocument(fullPath, string(content), updated); err != nil {
+		writeFailed(w, err)
+		return
+	}
+	a.MarkApplied(appliedBy, time.Now())
+	if err := annotation.Save(space.docsDir, file); err != nil {
+		http.Error(w, "写入批注失败: "+err.Error(), 500)
+		return
+	}
+
+	log.Printf("[批注] %s 采纳了 %s 的建议修改 %s", appliedBy, path, id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+func (s *Server) handleDebugIndex(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	w.Header().Set("Content-Type", "application/json")
+	index := space.idx.GetIndex()
+
+	type debugInfo struct {
+		TermCount    int                 `json:"termCount"`
+		FormulaCount int                 `json:"formulaCount"`
+		Scopes       []string            `json:"scopes"`
+		BuildTime    int64               `json:"buildTime"`
+		Terms        map[string][]string `json:"terms"`
+	}
+
+	// 简化词条信息：alias → [filePath, ...]
+	termsSimple := make(map[string][]string)
+	for alias, defs := range index.Terms {
+		var files []string
+		for _, d := range defs {
+			files = append(files, fmt.Sprintf("%s (%s)", d.FilePath, d.DefinitionType))
+		}
+		termsSimple[alias] = files
+	}
+
+	info := debugInfo{
+		TermCount:    len(index.Terms),
+		FormulaCount: len(index.Formulas),
+		Scopes:       index.Scopes,
+		BuildTime:    index.BuildTime,
+		Terms:        termsSimple,
+	}
+	json.NewEncoder(w).Encode(info)
+}
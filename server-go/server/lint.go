@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"xlxz-wiki/lint"
+)
+
+// Lint 检查工作区的文档，workspace 为空时检查默认工作区
+func (s *Server) Lint(workspace string) ([]lint.Issue, error) {
+	space, err := s.workspaceNamed(workspace)
+	if err != nil {
+		return nil, err
+	}
+	return lint.Run(space.idx, space.docsDir), nil
+}
+
+// LintFix 按检查结果中的替换建议修改文档并写回，返回修改的处数
+func (s *Server) LintFix(workspace string, issues []lint.Issue) (int, error) {
+	space, err := s.workspaceNamed(workspace)
+	if err != nil {
+		return 0, err
+	}
+	byFile := make(map[string][]lint.Issue)
+	for _, issue := range issues {
+		byFile[issue.File] = append(byFile[issue.File], issue)
+	}
+	total := 0
+	for relPath, list := range byFile {
+		fullPath, ok := space.resolveDocPath(relPath)
+		if !ok {
+			continue
+		}
+		content, err := os.ReadFile(fullPath)
+		if err != nil {
+			return total, err
+		}
+		fixed, n := lint.Fix(string(content), list)
+		if n == 0 {
+			continue
+		}
+		if err := space.rewriteDocument(fullPath, string(content), fixed); err != nil {
+			return total, fmt.Errorf("%s: %w", relPath, err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Bracketize 为工作区文档中未加括号的词条和别名加上 【】；write 为 false 时只返回修改，不写入文件
+func (s *Server) Bracketize(workspace string, write bool) ([]lint.Change, error) {
+	space, err := s.workspaceNamed(workspace)
+	if err != nil {
+		return nil, err
+	}
+	changes := lint.Bracketize(space.idx, space.docsDir)
+	if write {
+		for _, c := range changes {
+			fullPath, _ := space.resolveDocPath(c.File)
+			if err := space.rewriteDocument(fullPath, c.Old, c.New); err != nil {
+				return nil, fmt.Errorf("%s: %w", c.File, err)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// handleLinks 文档中未加 【】 的词条和别名的位置，前端据此自动链接（features.autoLink）
+func (s *Server) handleLinks(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "缺少 path 参数", 400)
+		return
+	}
+	relPath, content, ok := space.readDoc(w, path)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lint.NewChecker(space.idx.GetIndex()).Mentions(relPath, content))
+}
+
+// handleLint 检查文档：path 为空时检查整个工作区，否则只检查该文档
+func (s *Server) handleLint(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	var issues []lint.Issue
+	if path := r.URL.Query().Get("path"); path == "" {
+		issues = lint.Run(space.idx, space.docsDir)
+	} else {
+		relPath, content, ok := space.readDoc(w, path)
+		if !ok {
+			return
+		}
+		issues = lint.NewChecker(space.idx.GetIndex()).Check(relPath, content)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(issues)
+}
+
+// handleLintFix 快速修复：按请求中的问题（省略时为该文档全部可修复的问题）替换原文并写回，
+// 返回修改的处数和修改后仍存在的问题
+func (s *Server) handleLintFix(w http.ResponseWriter, r *http.Request) {
+	space := workspaceFrom(r)
+	if r.Method != "POST" {
+		http.Error(w, "仅支持 POST", 405)
+		return
+	}
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "缺少 path 参数", 400)
+		return
+	}
+	relPath, content, ok := space.readDoc(w, path)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Issues []lint.Issue `json:"issues"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		http.Error(w, "无效的请求体", 400)
+		return
+	}
+	checker := lint.NewChecker(space.idx.GetIndex())
+	if body.Issues == nil {
+		body.Issues = checker.Check(relPath, content)
+	}
+
+	fixed, n := lint.Fix(content, body.Issues)
+	if n > 0 {
+		fullPath, _ := space.resolveDocPath(path)
+		if err := space.rewriteDocument(fullPath, content, fixed); err != nil {
+			writeFailed(w, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"fixed":  n,
+		"issues": checker.Check(relPath, fixed),
+	})
+}
+
+// readDoc 读取文档，返回相对路径和内容；路径非法或文件不存在时写入错误响应
+func (space *workspace) readDoc(w http.ResponseWriter, path string) (relPath, content string, ok bool) {
+	fullPath, ok := space.resolveDocPath(path)
+	if !ok {
+		http.Error(w, "非法路径", 403)
+		return "", "", false
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		http.Error(w, "文件不存在", 404)
+		return "", "", false
+	}
+	relPath, _ = filepath.Rel(space.docsDir, fullPath)
+	return filepath.ToSlash(relPath), string(data), true
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"xlxz-wiki/config"
+)
+
+// route serveHandler 解析出的请求归属
+type route struct {
+	root  string     // 浏览器看到的 wiki 根路径
+	base  string     // 当前工作区的路径，默认工作区与 root 相同
+	space *workspace // 当前工作区
+}
+
+type routeKey struct{}
+
+// serveHandler 包装路由：处理反向代理请求头、路径前缀和工作区
+//
+// 请求路径带前缀（/wiki/api/index）或已被代理去掉前缀（/api/index）都能访问，
+// 因此反向代理无论是否 strip prefix 都不需要额外配置
+//
+// 工作区由路径 /w/名称/ 或参数 ?workspace=名称 指定，都没有时为默认工作区。
+// 前端页面通过 /w/名称/ 打开时注入的 <base> 带有该前缀，之后的请求都落在同一工作区
+func (s *Server) serveHandler(h http.Handler) http.Handler {
+	prefix := strings.TrimSuffix(s.cfg.BasePath, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := s.cfg.BasePath
+		if s.cfg.TrustProxy {
+			r = applyForwarded(r)
+			if p := r.Header.Get("X-Forwarded-Prefix"); p != "" {
+				base = config.NormalizeBasePath(p)
+			}
+		}
+
+		if prefix != "" {
+			switch {
+			case r.URL.Path == prefix:
+				http.Redirect(w, r, base, http.StatusMovedPermanently)
+				return
+			case strings.HasPrefix(r.URL.Path, prefix+"/"):
+				r = r.Clone(r.Context())
+				r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+				r.URL.RawPath = ""
+			}
+		}
+
+		rt := route{root: base, base: base, space: s.workspaces[0]}
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/w/"); ok {
+			name, tail, found := strings.Cut(rest, "/")
+			if rt.space = s.byName[name]; rt.space == nil {
+				http.Error(w, "未知工作区: "+name, 404)
+				return
+			}
+			rt.base = workspaceBase(base, rt.space)
+			if !found {
+				http.Redirect(w, r, rt.base, http.StatusMovedPermanently)
+				return
+			}
+			r = r.Clone(r.Context())
+			r.URL.Path = "/" + tail
+			r.URL.RawPath = ""
+		} else if name := r.URL.Query().Get("workspace"); name != "" {
+			if rt.space = s.byName[name]; rt.space == nil {
+				http.Error(w, "未知工作区: "+name, 404)
+				return
+			}
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, rt)))
+	})
+}
+
+// workspaceBase 工作区的访问路径：根路径/w/名称/
+func workspaceBase(root string, space *workspace) string {
+	return root + "w/" + url.PathEscape(space.name) + "/"
+}
+
+// workspaceFrom 返回请求所属的工作区
+func workspaceFrom(r *http.Request) *workspace {
+	return r.Context().Value(routeKey{}).(route).space
+}
+
+// applyForwarded 用 X-Forwarded-For/Host/Proto 还原客户端地址、浏览器访问的主机名和协议
+// 主机名用于 WebSocket 同源检查，协议（r.URL.Scheme）决定登录 Cookie 是否带 Secure
+// X-Forwarded-For 取最后一项：前面的项可以由客户端伪造，只有最后一项是受信任的代理自己添加的
+func applyForwarded(r *http.Request) *http.Request {
+	forwardedFor := lastHeaderValue(r, "X-Forwarded-For")
+	forwardedHost := firstHeaderValue(r, "X-Forwarded-Host")
+	forwardedProto := firstHeaderValue(r, "X-Forwarded-Proto")
+	if forwardedFor == "" && forwardedHost == "" && forwardedProto == "" {
+		return r
+	}
+	r = r.Clone(r.Context())
+	if ip := net.ParseIP(forwardedFor); ip != nil {
+		r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+	}
+	if forwardedHost != "" {
+		r.Host = forwardedHost
+	}
+	if strings.EqualFold(forwardedProto, "https") {
+		r.URL.Scheme = "https"
+	}
+	return r
+}
+
+// firstHeaderValue 多级代理时取最靠近客户端的一项
+func firstHeaderValue(r *http.Request, name string) string {
+	value, _, _ := strings.Cut(r.Header.Get(name), ",")
+	return strings.TrimSpace(value)
+}
+
+// lastHeaderValue 多级代理时取最后一级代理添加的一项（请求头出现多次时取最后一个）
+func lastHeaderValue(r *http.Request, name string) string {
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		return ""
+	}
+	list := values[len(values)-1]
+	return strings.TrimSpace(list[strings.LastIndex(list, ",")+1:])
+}
+
+// basePathFrom 返回浏览器看到的路径前缀
+func basePathFrom(r *http.Request) string {
+	if rt, ok := r.Context().Value(routeKey{}).(route); ok {
+		return rt.base
+	}
+	return "/"
+}
@@ -0,0 +1,298 @@
+// Package server 提供 wiki 的 HTTP 服务：REST API、WebSocket 实时推送和前端页面
+//
+// 既可以独立监听（Serve），也可以挂载到其他程序的路由下：
+//
+//	c := config.Default()
+//	c.Docs, c.BasePath = "/data/wiki-docs", "/wiki/"
+//	srv, err := server.New(server.Config{Config: *c, Assets: assets})
+//	go srv.Run(ctx)
+//	mux.Handle("/wiki/", srv)
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"xlxz-wiki/auth"
+	"xlxz-wiki/config"
+	"xlxz-wiki/ignore"
+	"xlxz-wiki/indexer"
+	"xlxz-wiki/watcher"
+	"xlxz-wiki/ws"
+)
+
+// shutdownTimeout 关闭时等待进行中的请求和 WebSocket 发送完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
+// Config 服务器配置
+type Config struct {
+	config.Config
+
+	// Assets 前端构建产物（dist 目录内容），为 nil 时页面提示先构建前端
+	Assets fs.FS
+	// Version 由 /api/version 和 /api/status 返回，默认为 dev
+	Version string
+	// SkipCacheWrite 只读取索引缓存而不写入，供 lint、bracketize 等一次性的命令行任务使用
+	SkipCacheWrite bool
+}
+
+// Server wiki 实例，可包含多个工作区；实例之间不共享状态
+type Server struct {
+	cfg        Config
+	ignore     *ignore.Matcher
+	auth       *auth.Authenticator
+	workspaces []*workspace // 第一个为默认工作区
+	byName     map[string]*workspace
+	handler    http.Handler
+}
+
+// workspace 一个文档目录：独立的索引、文件监听、批注和 WebSocket 连接
+type workspace struct {
+	name    string
+	docsDir string
+	idx     *indexer.WikiIndexer
+	hub     *ws.Hub
+	watcher *watcher.Watcher
+	locks   fileLocks
+}
+
+// New 校验配置、构建各工作区的索引并注册路由
+// 文件监听和 WebSocket 推送在 Run 或 Serve 中启动
+func New(c Config) (*Server, error) {
+	c.BasePath = config.NormalizeBasePath(c.BasePath)
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	list, _ := c.WorkspaceList()
+	if c.Version == "" {
+		c.Version = "dev"
+	}
+
+	s := &Server{
+		cfg:    c,
+		ignore: c.IgnoreMatcher(),
+		auth:   c.Authenticator(),
+		byName: make(map[string]*workspace),
+	}
+	for _, w := range list {
+		space, err := s.newWorkspace(w)
+		if err != nil {
+			return nil, err
+		}
+		s.workspaces = append(s.workspaces, space)
+		s.byName[space.name] = space
+	}
+
+	// 工作区之间互为只读词条来源，文档中以 【项目A:滑移】 引用
+	for _, space := range s.workspaces {
+		for _, other := range s.workspaces {
+			if other != space {
+				space.idx.AddSource(other.name, other.idx)
+			}
+		}
+		if len(s.workspaces) > 1 {
+			space.watcher.OnChange = func() { s.notifyExternal(space) }
+		}
+	}
+
+	s.handler = s.serveHandler(s.routes())
+	return s, nil
+}
+
+func (s *Server) newWorkspace(w config.Workspace) (*workspace, error) {
+	docsDir, err := filepath.Abs(w.Docs)
+	if err != nil {
+		return nil, err
+	}
+	space := &workspace{name: w.Name, docsDir: docsDir, hub: ws.NewHub()}
+	space.hub.AllowOrigins(s.cfg.AllowedOrigins)
+
+	space.idx = indexer.New(docsDir)
+	space.idx.SetIgnore(s.ignore)
+	space.idx.SetDefinitionLimit(s.cfg.Features.DefinitionLimit)
+	if s.cfg.Features.IndexCache {
+		space.idx.SetCache(filepath.Join(docsDir, indexer.CacheFileName))
+	}
+	if err := space.idx.BuildIndex(); err != nil {
+		log.Printf("[索引] %s 构建失败: %v", w.Name, err)
+	}
+	if !s.cfg.SkipCacheWrite {
+		space.saveCache()
+	}
+
+	space.watcher = watcher.New(docsDir, space.idx, space.hub)
+	space.watcher.ReconcileInterval = s.cfg.Features.ReconcileInterval
+	return space, nil
+}
+
+// saveCache 保存索引缓存，失败时只记录日志（下次启动重新解析）
+func (space *workspace) saveCache() {
+	if err := space.idx.SaveCache(); err != nil {
+		log.Printf("[索引] %s 保存缓存失败: %v", space.name, err)
+	}
+}
+
+// notifyExternal 通知其他工作区的客户端：space 的词条已变化，需要重新获取索引
+func (s *Server) notifyExternal(space *workspace) {
+	msg := struct {
+		Type    string `json:"type"`
+		Payload any    `json:"payload"`
+	}{Type: "external-updated", Payload: map[string]string{"workspace": space.name}}
+	data, _ := json.Marshal(msg)
+	for _, other := range s.workspaces {
+		if other != space {
+			other.hub.Broadcast(data)
+		}
+	}
+}
+
+// ServeHTTP 实现 http.Handler，挂载到其他路由时 BasePath 应与挂载路径一致
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Workspaces 各工作区的名称和文档目录（绝对路径），第一个为默认工作区
+func (s *Server) Workspaces() []config.Workspace {
+	list := make([]config.Workspace, len(s.workspaces))
+	for i, space := range s.workspaces {
+		list[i] = config.Workspace{Name: space.name, Docs: space.docsDir}
+	}
+	return list
+}
+
+// Authenticator 访问控制，用于输出启动信息
+func (s *Server) Authenticator() *auth.Authenticator {
+	return s.auth
+}
+
+// Run 运行各工作区的文件监听和 WebSocket 推送直到 ctx 取消，然后处理完当前批次的文件变更、
+// 保存索引缓存、向客户端发送完已排队的消息后断开连接。挂载到其他路由时由调用方启动，只能调用一次
+func (s *Server) Run(ctx context.Context) {
+	var watchers sync.WaitGroup
+	for _, space := range s.workspaces {
+		watchers.Add(1)
+		go func() {
+			defer watchers.Done()
+			space.watcher.Run(ctx)
+		}()
+		go space.hub.Run()
+	}
+
+	watchers.Wait()
+	for _, space := range s.workspaces {
+		if !s.cfg.SkipCacheWrite {
+			space.saveCache()
+		}
+	}
+	closeCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, space := range s.workspaces {
+		if err := space.hub.Close(closeCtx); err != nil {
+			log.Printf("[WebSocket] %s 关闭连接超时: %v", space.name, err)
+		}
+	}
+}
+
+// Serve 在 ln 上提供服务直到 ctx 取消，然后依次：
+// 停止接受新请求并等待进行中的请求（如保存文档）完成、
+// 停止文件监听（先处理完当前批次并广播）、
+// 向 WebSocket 客户端发送完已排队的消息后发送 close 帧
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	runCtx, stopRun := context.WithCancel(context.Background())
+	defer stopRun()
+	runDone := make(chan struct{})
+	go func() {
+		s.Run(runCtx)
+		close(runDone)
+	}()
+
+	httpServer := &http.Server{Handler: s}
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.cfg.TLS.Enabled() {
+			serveErr <- httpServer.ServeTLS(ln, s.cfg.TLS.Cert, s.cfg.TLS.Key)
+		} else {
+			serveErr <- httpServer.Serve(ln)
+		}
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		log.Printf("[服务] 正在关闭...")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[服务] 等待请求完成超时: %v", err)
+	}
+	stopRun()
+	<-runDone
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// routes 注册路由：按角色放行，读请求与修改请求分别要求不同角色
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/index", s.guard(auth.Viewer, auth.Viewer, s.handleIndex))
+	mux.HandleFunc("/api/file", s.guard(auth.Viewer, auth.Editor, s.handleFile))
+	mux.HandleFunc("/api/file/status", s.guard(auth.Viewer, auth.Editor, s.handleFileStatus))
+	mux.HandleFunc("/api/files", s.guard(auth.Viewer, auth.Viewer, s.handleFiles))
+	mux.HandleFunc("/api/render", s.guard(auth.Viewer, auth.Viewer, s.handleRender))
+	mux.HandleFunc("/api/outline", s.guard(auth.Viewer, auth.Viewer, s.handleOutline))
+	mux.HandleFunc("/api/search", s.guard(auth.Viewer, auth.Viewer, s.handleSearch))
+	mux.HandleFunc("/api/tags", s.guard(auth.Viewer, auth.Viewer, s.handleTags))
+	mux.HandleFunc("/api/lint", s.guard(auth.Viewer, auth.Viewer, s.handleLint))
+	mux.HandleFunc("/api/lint/fix", s.guard(auth.Editor, auth.Editor, s.handleLintFix))
+	mux.HandleFunc("/api/version", s.guard(auth.None, auth.None, s.handleVersion))
+	mux.HandleFunc("/api/status", s.guard(auth.Viewer, auth.Viewer, s.handleStatus))
+	mux.HandleFunc("/api/workspaces", s.guard(auth.Viewer, auth.Viewer, s.handleWorkspaces))
+	if s.cfg.Features.AutoLink {
+		mux.HandleFunc("/api/links", s.guard(auth.Viewer, auth.Viewer, s.handleLinks))
+	}
+	if s.cfg.Features.DebugAPI {
+		mux.HandleFunc("/api/debug/index", s.guard(auth.Viewer, auth.Viewer, s.handleDebugIndex))
+	}
+	if s.cfg.Features.Annotations {
+		mux.HandleFunc("/api/annotations", s.guard(auth.Viewer, auth.Reviewer, s.handleAnnotations))
+		mux.HandleFunc("/api/annotations/export", s.guard(auth.Viewer, auth.Viewer, s.handleAnnotationsExport))
+		mux.HandleFunc("/api/annotations/apply", s.guard(auth.Editor, auth.Editor, s.handleAnnotationApply))
+	}
+	mux.HandleFunc("/ws", s.guard(auth.Viewer, auth.Viewer, func(w http.ResponseWriter, r *http.Request) {
+		ws.ServeWs(workspaceFrom(r).hub, w, r)
+	}))
+	mux.HandleFunc("/", s.guard(auth.Viewer, auth.Viewer, s.handleStatic))
+	return mux
+}
+
+// guard 读请求（GET/HEAD）要求 read 角色，其余请求要求 write 角色
+// 只读模式下直接拒绝修改请求
+func (s *Server) guard(read, write auth.Role, h http.HandlerFunc) http.HandlerFunc {
+	readHandler := s.auth.Require(read, h)
+	writeHandler := s.auth.Require(write, h)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" {
+			readHandler(w, r)
+			return
+		}
+		if s.cfg.ReadOnly {
+			http.Error(w, "服务器处于只读模式", 403)
+			return
+		}
+		writeHandler(w, r)
+	}
+}
@@ -0,0 +1,762 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"xlxz-wiki/annotation"
+	"xlxz-wiki/config"
+	"xlxz-wiki/indexer"
+)
+
+// testConfig 在临时目录中写入文档，返回指向该目录的默认配置
+func testConfig(t *testing.T, files map[string]string) Config {
+	t.Helper()
+	docs := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(docs, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := config.Default()
+	c.Docs = docs
+	c.Features.ReconcileInterval = 0
+	return Config{Config: *c}
+}
+
+func newTestServer(t *testing.T, c Config) *Server {
+	t.Helper()
+	s, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// termNames 返回 /api/index 中的词条名
+func termNames(t *testing.T, url string) []string {
+	t.Helper()
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var index struct {
+		Terms map[string]json.RawMessage `json:"terms"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&index); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range index.Terms {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	c := testConfig(t, nil)
+	c.Docs = ""
+	if _, err := New(c); err == nil {
+		t.Error("docs 为空应返回错误")
+	}
+}
+
+func TestServer_IsolatedInstances(t *testing.T) {
+	ca := testConfig(t, map[string]string{"滑移.md": "滑移的定义\n"})
+	cb := testConfig(t, map[string]string{"冲击.md": "冲击的定义\n"})
+	ca.Auth.Anonymous, cb.Auth.Anonymous = "editor", "editor"
+	a, b := newTestServer(t, ca), newTestServer(t, cb)
+	tsA, tsB := httptest.NewServer(a), httptest.NewServer(b)
+	defer tsA.Close()
+	defer tsB.Close()
+
+	if got := termNames(t, tsA.URL+"/api/index"); strings.Join(got, ",") != "滑移" {
+		t.Errorf("A terms = %v", got)
+	}
+	if got := termNames(t, tsB.URL+"/api/index"); strings.Join(got, ",") != "冲击" {
+		t.Errorf("B terms = %v", got)
+	}
+
+	res, err := http.Post(tsB.URL+"/api/file?path=新.md", "application/json", strings.NewReader(`{"content":"新内容"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if _, err := os.Stat(filepath.Join(ca.Docs, "新.md")); err == nil {
+		t.Error("写入 B 的文档出现在 A 的目录中")
+	}
+	if content, _ := os.ReadFile(filepath.Join(cb.Docs, "新.md")); string(content) != "新内容" {
+		t.Errorf("B 的文档内容 = %q", content)
+	}
+}
+
+func TestServer_MountUnderMux(t *testing.T) {
+	c := testConfig(t, map[string]string{"滑移.md": "滑移的定义\n"})
+	c.BasePath = "/wiki/"
+	assets := t.TempDir()
+	os.WriteFile(filepath.Join(assets, "index.html"), []byte("<html><head></head></html>"), 0644)
+	c.Assets = os.DirFS(assets)
+	wiki := newTestServer(t, c)
+
+	mux := http.NewServeMux()
+	mux.Handle("/wiki/", wiki)
+	mux.HandleFunc("/portal", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("portal")) })
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	if got := termNames(t, ts.URL+"/wiki/api/index"); strings.Join(got, ",") != "滑移" {
+		t.Errorf("terms = %v", got)
+	}
+	res, err := http.Get(ts.URL + "/wiki/doc/滑移.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), `<base href="/wiki/">`) {
+		t.Errorf("SPA 回退页未注入 base: %s", body)
+	}
+	res, _ = http.Get(ts.URL + "/portal")
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Errorf("/portal: %d", res.StatusCode)
+	}
+}
+
+func TestServer_SiblingWorkspacePath(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"wiki/滑移.md":   "滑移的定义\n",
+		"wiki-b/冲击.md": "冲击的定义\n",
+	})
+	c.Workspaces = []string{"项目A:" + filepath.Join(c.Docs, "wiki"), "项目B:" + filepath.Join(c.Docs, "wiki-b")}
+	ts := httptest.NewServer(newTestServer(t, c))
+	defer ts.Close()
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"滑移.md", 200},
+		{"../wiki-b/冲击.md", 403}, // 名称前缀相同的相邻工作区
+		{"../wiki/滑移.md", 200},   // 绕回自身目录
+		{"..", 403},
+	}
+	for _, tt := range tests {
+		res, err := http.Get(ts.URL + "/api/file?path=" + url.QueryEscape(tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.want {
+			t.Errorf("%s: %d, want %d", tt.path, res.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestServer_Workspaces(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"a/滑移.md": "滑移的定义\n",
+		"b/冲击.md": "冲击的定义\n",
+	})
+	c.Workspaces = []string{"项目A:" + filepath.Join(c.Docs, "a"), "项目B:" + filepath.Join(c.Docs, "b")}
+	c.Auth.Anonymous = "editor"
+	assets := t.TempDir()
+	os.WriteFile(filepath.Join(assets, "index.html"), []byte("<html><head></head></html>"), 0644)
+	c.Assets = os.DirFS(assets)
+	ts := httptest.NewServer(newTestServer(t, c))
+	defer ts.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/index", "滑移"}, // 默认工作区
+		{"/w/项目B/api/index", "冲击"},
+		{"/api/index?workspace=项目B", "冲击"},
+		{"/w/项目A/api/index", "滑移"},
+	}
+	for _, tt := range tests {
+		if got := termNames(t, ts.URL+tt.path); strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: terms = %v, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{"/w/项目C/api/index", "/api/index?workspace=项目C"} {
+		res, _ := http.Get(ts.URL + path)
+		res.Body.Close()
+		if res.StatusCode != 404 {
+			t.Errorf("%s: %d, want 404", path, res.StatusCode)
+		}
+	}
+
+	// 工作区页面注入带前缀的 <base>，前端之后的请求都落在该工作区
+	res, _ := http.Get(ts.URL + "/w/项目B/doc/冲击.md")
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), `<base href="/w/%E9%A1%B9%E7%9B%AEB/">`) {
+		t.Errorf("base 未带工作区前缀: %s", body)
+	}
+
+	res, _ = http.Get(ts.URL + "/w/项目B/api/workspaces")
+	var list struct {
+		Current    string `json:"current"`
+		Workspaces []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"workspaces"`
+	}
+	json.NewDecoder(res.Body).Decode(&list)
+	res.Body.Close()
+	if list.Current != "项目B" || len(list.Workspaces) != 2 ||
+		list.Workspaces[0].URL != "/" || list.Workspaces[1].URL != "/w/%E9%A1%B9%E7%9B%AEB/" {
+		t.Errorf("workspaces = %+v", list)
+	}
+
+	// 写入只落在请求的工作区
+	res, _ = http.Post(ts.URL+"/w/项目B/api/file?path=新.md", "application/json", strings.NewReader(`{"content":"x"}`))
+	res.Body.Close()
+	if _, err := os.Stat(filepath.Join(c.Docs, "b", "新.md")); err != nil {
+		t.Errorf("未写入项目B: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(c.Docs, "a", "新.md")); err == nil {
+		t.Error("写入了项目A")
+	}
+}
+
+func TestServer_CrossWorkspaceTerms(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"shared/滑移.md":  "通用的滑移定义\n",
+		"project/冲击.md": "【通用术语:滑移】后产生冲击\n",
+	})
+	c.Workspaces = []string{"项目A:" + filepath.Join(c.Docs, "project"), "通用术语:" + filepath.Join(c.Docs, "shared")}
+	srv := newTestServer(t, c)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index struct {
+		Terms    map[string]json.RawMessage `json:"terms"`
+		External map[string]map[string][]struct {
+			FilePath  string `json:"filePath"`
+			Workspace string `json:"workspace"`
+		} `json:"external"`
+	}
+	json.NewDecoder(res.Body).Decode(&index)
+	res.Body.Close()
+	if _, ok := index.Terms["滑移"]; ok {
+		t.Error("其他工作区的词条不应出现在 terms 中")
+	}
+	defs := index.External["通用术语"]["滑移"]
+	if len(defs) != 1 || defs[0].Workspace != "通用术语" || defs[0].FilePath != "滑移.md" {
+		t.Errorf("external = %+v", index.External)
+	}
+
+	// 其他工作区的词条变化时通知本工作区的客户端重新获取索引
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Run(ctx)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(filepath.Join(c.Docs, "shared", "冲击.md"), []byte("通用的冲击定义\n"), 0644)
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(msg), `"external-updated"`) || !strings.Contains(string(msg), "通用术语") {
+		t.Errorf("msg = %s", msg)
+	}
+}
+
+func TestGuard_ReadOnlyRejectsWrites(t *testing.T) {
+	c := testConfig(t, map[string]string{"a.md": "内容\n"})
+	c.ReadOnly = true
+	s := newTestServer(t, c)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/file?path=a.md", nil))
+	if rec.Code != 200 {
+		t.Errorf("只读模式下 GET 应放行，得到 %d", rec.Code)
+	}
+
+	for _, method := range []string{"POST", "PUT", "DELETE"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(method, "/api/file?path=a.md", strings.NewReader("{}")))
+		if rec.Code != 403 {
+			t.Errorf("只读模式下 %s 应被拒绝，得到 %d", method, rec.Code)
+		}
+	}
+}
+
+func TestServer_Render(t *testing.T) {
+	c := testConfig(t, map[string]string{"冲击.md": "---\nscope: 战斗\n---\n受到【滑移】后触发\n\n<!-- more -->\n\n细节\n"})
+	s := newTestServer(t, c)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/render?path=冲击.md&summary=1", nil))
+	body := rec.Body.String()
+	if rec.Code != 200 || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("状态 %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, `<p data-line="4">受到<span class="wiki-term" data-term="滑移">`) || strings.Contains(body, "细节") {
+		t.Errorf("渲染结果不符: %s", body)
+	}
+
+	for target, code := range map[string]int{"/api/render": 400, "/api/render?path=不存在.md": 404, "/api/render?path=../x.md": 403} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != code {
+			t.Errorf("%s: 期望 %d，得到 %d", target, code, rec.Code)
+		}
+	}
+}
+
+func TestServer_Outline(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"战斗/冲击.md": "# 冲击\n\n## 判定 {#rules}\n\n#### 例外\n\n## 数值\n",
+		"空.md":     "没有标题\n",
+	})
+	s := newTestServer(t, c)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/outline?path=战斗/冲击.md", nil))
+	var outline []*indexer.OutlineItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &outline); err != nil || rec.Code != 200 {
+		t.Fatalf("状态 %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(outline) != 1 || len(outline[0].Children) != 2 {
+		t.Fatalf("大纲结构不符: %s", rec.Body.String())
+	}
+	rules := outline[0].Children[0]
+	if rules.Slug != "rules" || rules.Line != 3 || len(rules.Children) != 1 || rules.Children[0].Text != "例外" {
+		t.Errorf("判定 = %+v", rules)
+	}
+
+	for target, want := range map[string]string{"/api/outline?path=空.md": "[]\n", "/api/outline?path=不存在.md": "文件不存在\n"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Body.String() != want {
+			t.Errorf("%s: 得到 %d %q", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestServer_TagFilters(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"战斗/冲击.md": "---\ntags: PvP\n---\n冲击定义\n",
+		"战斗/滑移.md": "滑移定义\n",
+		"背包.md":    "---\ntags: PvP, 需要程序确认\n---\n背包定义\n",
+	})
+	s := newTestServer(t, c)
+
+	get := func(target string, v any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil || rec.Code != 200 {
+			t.Fatalf("%s: 状态 %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+
+	var tags []indexer.TagInfo
+	get("/api/tags", &tags)
+	if len(tags) != 2 || tags[0].Tag != "PvP" || tags[0].Count != 2 {
+		t.Errorf("/api/tags = %+v", tags)
+	}
+
+	var tree []*FileTreeNode
+	get("/api/files?tag=PvP", &tree)
+	if len(tree) != 2 || len(tree[0].Children) != 1 || tree[0].Children[0].Path != "战斗/冲击.md" || tree[1].Tags[1] != "需要程序确认" {
+		t.Errorf("/api/files?tag=PvP 结构不符")
+	}
+	get("/api/files?tag=PvP&tag=需要程序确认", &tree)
+	if len(tree) != 1 || tree[0].Path != "背包.md" {
+		t.Errorf("多个标签应同时满足")
+	}
+
+	var results []*indexer.WikiTerm
+	get("/api/search?tag=需要程序确认", &results)
+	if len(results) != 1 || results[0].Term != "背包" {
+		t.Errorf("/api/search?tag= = %+v", results)
+	}
+}
+
+func TestServer_FileStatus(t *testing.T) {
+	c := testConfig(t, map[string]string{"冲击.md": "---\nscope: 战斗\nstatus: draft\n---\n冲击定义\n"})
+	c.Auth.Anonymous = "editor"
+	s := newTestServer(t, c)
+
+	do := func(method, body string) (int, string) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(method, "/api/file/status?path=冲击.md", strings.NewReader(body)))
+		return rec.Code, rec.Body.String()
+	}
+
+	if code, body := do("GET", ""); code != 200 || body != `{"next":["review"],"path":"冲击.md","status":"draft"}`+"\n" {
+		t.Errorf("GET: %d %s", code, body)
+	}
+	if code, _ := do("POST", `{"status":"approved"}`); code != 409 {
+		t.Errorf("跳过审核: 期望 409，得到 %d", code)
+	}
+	if code, _ := do("POST", `{"status":"定稿"}`); code != 400 {
+		t.Errorf("未知状态: 期望 400，得到 %d", code)
+	}
+	if code, body := do("POST", `{"status":"review"}`); code != 200 || !strings.Contains(body, `"status":"review"`) {
+		t.Errorf("POST: %d %s", code, body)
+	}
+	content, _ := os.ReadFile(filepath.Join(c.Docs, "冲击.md"))
+	if string(content) != "---\nscope: 战斗\nstatus: review\n---\n冲击定义\n" {
+		t.Errorf("写入结果 %q", content)
+	}
+}
+
+func TestServer_LintFix(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"拆除.md": "---\nsynonyms-forbidden: [删除]\n---\n移除建筑\n",
+		"玩法.md": "可以删除，也可以拆除。\n",
+	})
+	c.Auth.Anonymous = "editor"
+	s := newTestServer(t, c)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/lint?path=玩法.md", nil))
+	var issues []struct {
+		Line, Column int
+		Rule         string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &issues); err != nil || len(issues) != 2 || issues[0].Rule != "forbidden-synonym" || issues[1].Column != 9 {
+		t.Fatalf("GET /api/lint: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/api/lint/fix?path=玩法.md", nil))
+	if rec.Code != 200 || rec.Body.String() != `{"fixed":2,"issues":[]}`+"\n" {
+		t.Errorf("POST /api/lint/fix: %d %s", rec.Code, rec.Body)
+	}
+	content, _ := os.ReadFile(filepath.Join(c.Docs, "玩法.md"))
+	if string(content) != "可以【拆除】，也可以【拆除】。\n" {
+		t.Errorf("写入结果 %q", content)
+	}
+
+	for target, want := range map[string]int{"/api/lint?path=无.md": 404, "/api/lint?path=../x.md": 403} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != want {
+			t.Errorf("%s: 期望 %d，得到 %d", target, want, rec.Code)
+		}
+	}
+}
+
+func TestServer_AnnotationApply(t *testing.T) {
+	c := testConfig(t, map[string]string{
+		"冲击.md": "冲击会打断施法，非常强力。\n",
+		// 文件中记录的 filePath 与所在位置不一致时，仍写回该文档的批注文件
+		".annotations/冲击.json": `{"version":1,"filePath":"其他.md","annotations":[
+			{"id":"a","selectedText":"，非常强力","comment":"删去","suggestion":"","anchorLine":1,"status":"open"},
+			{"id":"b","selectedText":"已删除的原文","comment":"过期","suggestion":"新文","anchorLine":1,"status":"open"}]}`,
+	})
+	c.Auth.Users = []string{"小林:edit-key:editor", "小王:review-key:reviewer"}
+	s := newTestServer(t, c)
+
+	apply := func(id, key string) int {
+		req := httptest.NewRequest("POST", "/api/annotations/apply?path=冲击.md&id="+id, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := apply("a", "review-key"); code != 403 {
+		t.Errorf("reviewer 采纳: 期望 403，得到 %d", code)
+	}
+	if code := apply("b", "edit-key"); code != 409 {
+		t.Errorf("原文已变更: 期望 409，得到 %d", code)
+	}
+	if code := apply("a", "edit-key"); code != 200 {
+		t.Fatalf("采纳: 期望 200，得到 %d", code)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(c.Docs, "冲击.md"))
+	if string(content) != "冲击会打断施法。\n" {
+		t.Errorf("写入结果 %q", content)
+	}
+	file, err := annotation.Load(c.Docs, "冲击.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := file.Find("a"); file.FilePath != "冲击.md" || a.Status != "resolved" || a.AppliedBy != "小林" {
+		t.Errorf("批注文件 = %+v, a = %+v", file, a)
+	}
+	if _, err := os.Stat(annotation.Path(c.Docs, "其他.md")); !os.IsNotExist(err) {
+		t.Error("不应按文件中记录的 filePath 写入其他批注文件")
+	}
+
+	// 过期页面整体保存批注列表时不能把已采纳的批注改回待处理
+	stale := `{"version":1,"filePath":"冲击.md","annotations":[
+		{"id":"a","selectedText":"，非常强力","comment":"删去（已编辑）","suggestion":"","anchorLine":1,"status":"open"}]}`
+	req := httptest.NewRequest("POST", "/api/annotations?path=冲击.md", strings.NewReader(stale))
+	req.Header.Set("Authorization", "Bearer review-key")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("保存批注: %d %s", rec.Code, rec.Body)
+	}
+	file, _ = annotation.Load(c.Docs, "冲击.md")
+	if a := file.Find("a"); a == nil || a.Status != "resolved" || a.AppliedBy != "小林" || a.Comment != "删去（已编辑）" {
+		t.Errorf("保存后 a = %+v", a)
+	}
+	if code := apply("a", "edit-key"); code != 409 {
+		t.Errorf("重复采纳: 期望 409，得到 %d", code)
+	}
+}
+
+func TestWorkspace_RewriteDocument(t *testing.T) {
+	c := testConfig(t, map[string]string{"冲击.md": "原文\n"})
+	space := newTestServer(t, c).workspaces[0]
+	path := filepath.Join(c.Docs, "冲击.md")
+
+	// 同时基于同一份原文改写，只有一个能写入
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	written := 0
+	for i := range 10 {
+		wg.Go(func() {
+			err := space.rewriteDocument(path, "原文\n", fmt.Sprintf("改写 %d\n", i))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				written++
+			case !errors.Is(err, errDocumentChanged):
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if written != 1 {
+		t.Errorf("写入 %d 次，期望 1 次", written)
+	}
+}
+
+func TestServer_Links(t *testing.T) {
+	files := map[string]string{"冲击.md": "冲击定义\n", "技能.md": "释放冲击，`冲击` 和 [说明](冲击.md) 不链接\n"}
+	s := newTestServer(t, testConfig(t, files))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/links?path=技能.md", nil))
+	if rec.Code == 200 && strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Error("未开启 features.autoLink 时不应提供 /api/links")
+	}
+
+	c := testConfig(t, files)
+	c.Features.AutoLink = true
+	s = newTestServer(t, c)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/links?path=技能.md", nil))
+	if rec.Code != 200 || rec.Body.String() != `[{"line":1,"column":3,"text":"冲击","term":"冲击"}]`+"\n" {
+		t.Errorf("/api/links: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestServer_IndexCache(t *testing.T) {
+	files := map[string]string{"冲击.md": "冲击定义\n"}
+	c := testConfig(t, files)
+	newTestServer(t, c)
+	if index, err := indexer.ReadCache(c.Docs); err != nil || len(index.Terms["冲击"]) != 1 {
+		t.Errorf("启动后应写入索引缓存: %v", err)
+	}
+
+	// 缓存不是对文档的修改，只读实例同样写入，下次启动才能直接使用
+	c = testConfig(t, files)
+	c.ReadOnly = true
+	newTestServer(t, c)
+	if _, err := indexer.ReadCache(c.Docs); err != nil {
+		t.Errorf("只读模式下也应写入索引缓存: %v", err)
+	}
+
+	c = testConfig(t, files)
+	c.Features.IndexCache = false
+	newTestServer(t, c)
+	if _, err := os.Stat(filepath.Join(c.Docs, indexer.CacheFileName)); !os.IsNotExist(err) {
+		t.Errorf("关闭 features.indexCache 时不应写入缓存: %v", err)
+	}
+}
+
+// echoHandler 返回去掉前缀后的路径、客户端地址和注入后的 index.html
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Remote", r.RemoteAddr)
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-Scheme", r.URL.Scheme)
+		w.Write(indexHTML([]byte("<html><head><title>t</title></head></html>"), r))
+	})
+}
+
+// proxyServer 只用于测试 serveHandler 的实例
+func proxyServer(update func(c *config.Config)) *Server {
+	c := config.Default()
+	update(c)
+	space := &workspace{name: config.DefaultWorkspace}
+	return &Server{cfg: Config{Config: *c}, workspaces: []*workspace{space}}
+}
+
+func TestServeHandler_BasePath(t *testing.T) {
+	s := proxyServer(func(c *config.Config) { c.BasePath = "/wiki/" })
+	h := s.serveHandler(echoHandler())
+
+	tests := []struct {
+		path     string
+		wantPath string
+	}{
+		{"/wiki/api/index", "/api/index"},
+		{"/wiki/", "/"},
+		{"/api/index", "/api/index"}, // 代理已去掉前缀
+		{"/wiki/doc/%E6%BB%91%E7%A7%BB.md", "/doc/滑移.md"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if got := rec.Header().Get("X-Path"); got != tt.wantPath {
+			t.Errorf("%s: path = %q, want %q", tt.path, got, tt.wantPath)
+		}
+		if !strings.Contains(rec.Body.String(), `<base href="/wiki/">`) {
+			t.Errorf("%s: base 未注入: %s", tt.path, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/wiki", nil))
+	if rec.Code != 301 || rec.Header().Get("Location") != "/wiki/" {
+		t.Errorf("/wiki 应重定向到 /wiki/，得到 %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestServeHandler_ForwardedHeaders(t *testing.T) {
+	req := func() *http.Request {
+		r := httptest.NewRequest("GET", "/api/index", nil)
+		r.RemoteAddr = "10.0.0.2:5000"
+		// 第一项由客户端伪造，最后一项才是代理看到的地址
+		r.Header.Set("X-Forwarded-For", "6.6.6.6, 192.168.1.20")
+		r.Header.Set("X-Forwarded-Host", "wiki.example.com")
+		r.Header.Set("X-Forwarded-Prefix", "/team/wiki")
+		r.Header.Set("X-Forwarded-Proto", "https")
+		return r
+	}
+
+	// 未开启 trustProxy 时忽略代理请求头
+	s := proxyServer(func(c *config.Config) {})
+	rec := httptest.NewRecorder()
+	s.serveHandler(echoHandler()).ServeHTTP(rec, req())
+	if rec.Header().Get("X-Remote") != "10.0.0.2:5000" || rec.Header().Get("X-Scheme") != "" || strings.Contains(rec.Body.String(), "/team/wiki/") {
+		t.Errorf("不应信任代理请求头: remote=%s body=%s", rec.Header().Get("X-Remote"), rec.Body.String())
+	}
+
+	s = proxyServer(func(c *config.Config) { c.TrustProxy = true })
+	rec = httptest.NewRecorder()
+	s.serveHandler(echoHandler()).ServeHTTP(rec, req())
+	if got := rec.Header().Get("X-Remote"); got != "192.168.1.20:0" {
+		t.Errorf("remote = %q", got)
+	}
+	if got := rec.Header().Get("X-Host"); got != "wiki.example.com" {
+		t.Errorf("host = %q", got)
+	}
+	if got := rec.Header().Get("X-Scheme"); got != "https" {
+		t.Errorf("scheme = %q", got)
+	}
+	if !strings.Contains(rec.Body.String(), `<base href="/team/wiki/">`) {
+		t.Errorf("应使用 X-Forwarded-Prefix 作为 base: %s", rec.Body.String())
+	}
+}
+
+func TestServer_GracefulShutdown(t *testing.T) {
+	c := testConfig(t, map[string]string{"滑移.md": "滑移的定义\n"})
+	srv := newTestServer(t, c)
+	docs := c.Docs
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx, ln) }()
+
+	base := "http://" + ln.Addr().String()
+	res, err := http.Get(base + "/api/search?q=滑移")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Fatalf("search: %d", res.StatusCode)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+ln.Addr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// 关闭前刚写入的文件：监听停止时应处理完当前批次并推送给客户端
+	os.WriteFile(filepath.Join(docs, "冲击.md"), []byte("冲击的定义\n"), 0644)
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	var gotChange bool
+	var closeErr *websocket.CloseError
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("应收到 close 帧，得到 %v", err)
+			}
+			break
+		}
+		gotChange = gotChange || strings.Contains(string(msg), "冲击.md")
+	}
+	if closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("close code = %d, want %d", closeErr.Code, websocket.CloseGoingAway)
+	}
+	if !gotChange {
+		t.Error("关闭前未推送最后一批变更")
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve 返回 %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve 未返回")
+	}
+	if _, err := http.Get(base + "/api/index"); err == nil {
+		t.Error("关闭后仍在接受请求")
+	}
+}
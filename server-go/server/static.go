@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// handleStatic 提供前端资源，找不到的路径回退到 index.html（SPA 路由）
+func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Assets == nil {
+		w.WriteHeader(404)
+		w.Write([]byte("XLXZ Wiki — 前端资源未构建，请先运行 pnpm build"))
+		return
+	}
+
+	path := r.URL.Path
+	// 尝试读取静态文件
+	if path != "/" {
+		filePath := strings.TrimPrefix(path, "/")
+		if content, err := fs.ReadFile(s.cfg.Assets, filePath); err == nil {
+			w.Header().Set("Content-Type", getMimeType(filePath))
+			w.Write(content)
+			return
+		}
+	}
+	// SPA 回退：返回 index.html
+	indexContent, err := fs.ReadFile(s.cfg.Assets, "index.html")
+	if err != nil {
+		http.Error(w, "index.html 不存在", 404)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML(indexContent, r))
+}
+
+// indexHTML 在 index.html 中注入 <base>，前端据此拼接资源、API、WebSocket 和路由地址
+func indexHTML(content []byte, r *http.Request) []byte {
+	tag := fmt.Sprintf("<head>\n    <base href=\"%s\">", html.EscapeString(basePathFrom(r)))
+	return bytes.Replace(content, []byte("<head>"), []byte(tag), 1)
+}
+
+func getMimeType(filePath string) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch ext {
+	case ".html":
+		return "text/html; charset=utf-8"
+	case ".css":
+		return "text/css; charset=utf-8"
+	case ".js":
+		return "application/javascript; charset=utf-8"
+	case ".json":
+		return "application/json; charset=utf-8"
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".svg":
+		return "image/svg+xml"
+	case ".ico":
+		return "image/x-icon"
+	case ".woff":
+		return "font/woff"
+	case ".woff2":
+		return "font/woff2"
+	case ".ttf":
+		return "font/ttf"
+	default:
+		return "application/octet-stream"
+	}
+}
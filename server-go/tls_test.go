@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureSelfSignedCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "a.crt"), filepath.Join(dir, "a.key")
+	if err := ensureSelfSignedCert(certFile, keyFile, "192.168.1.5"); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("生成的证书无法加载: %v", err)
+	}
+	if pair.Leaf == nil || pair.Leaf.VerifyHostname("192.168.1.5") != nil || pair.Leaf.VerifyHostname("localhost") != nil {
+		t.Error("证书应覆盖 localhost 和监听地址")
+	}
+
+	// 已存在时不重新生成
+	before := pair.Leaf.SerialNumber
+	ensureSelfSignedCert(certFile, keyFile, "")
+	again, _ := tls.LoadX509KeyPair(certFile, keyFile)
+	if again.Leaf.SerialNumber.Cmp(before) != 0 {
+		t.Error("已有证书被覆盖")
+	}
+}
@@ -0,0 +1,146 @@
+package watcher
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+// DefaultReconcileInterval 默认对账扫描间隔
+const DefaultReconcileInterval = 30 * time.Second
+
+// maxDriftSample 状态接口中最多展示的漂移文件数
+const maxDriftSample = 20
+
+// Stats 监听与对账的运行状态
+type Stats struct {
+	WatchedDirs  int      `json:"watchedDirs"`
+	WatchErrors  int      `json:"watchErrors"`
+	LastError    string   `json:"lastError,omitempty"`
+	LastErrorAt  string   `json:"lastErrorAt,omitempty"`
+	IntervalMs   int64    `json:"reconcileIntervalMs"`
+	Scans        int      `json:"scans"`
+	LastScanAt   string   `json:"lastScanAt,omitempty"`
+	LastScanMs   int64    `json:"lastScanMs"`
+	DriftFiles   int      `json:"driftFiles"`
+	LastDrift    []string `json:"lastDrift,omitempty"`
+	FullRebuilds int      `json:"fullRebuilds"`
+	LastRebuild  string   `json:"lastRebuildAt,omitempty"`
+}
+
+// Stats 返回当前运行状态的副本
+func (w *Watcher) Stats() Stats {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+	s := w.stats
+	s.IntervalMs = w.ReconcileInterval.Milliseconds()
+	s.LastDrift = append([]string(nil), w.stats.LastDrift...)
+	return s
+}
+
+func (w *Watcher) updateStats(update func(s *Stats)) {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+	update(&w.stats)
+}
+
+// handleError 监听出错（包括事件队列溢出）后可能已丢失事件：
+// 重新补齐目录监听，并请求全量重建索引
+func (w *Watcher) handleError(err error) {
+	log.Printf("[监听] 错误: %v", err)
+	w.updateStats(func(s *Stats) {
+		s.WatchErrors++
+		s.LastError = err.Error()
+		s.LastErrorAt = time.Now().Format(time.RFC3339)
+	})
+
+	if err := w.addDir(w.rootDir); err != nil {
+		log.Printf("[监听] 重新添加目录失败: %v", err)
+	}
+	select {
+	case w.rebuild <- struct{}{}:
+	default: // 已有待处理的重建请求
+	}
+}
+
+// reconcile 对比文件 mtime/哈希与索引记录，重新索引漂移的文件
+// 还在当前批次或等待配对的重命名中的文件由批次处理，这里跳过，避免重复索引和广播
+func (w *Watcher) reconcile() {
+	start := time.Now()
+	drift := w.idx.Drift()
+	drift.Added = w.withoutPending(drift.Added)
+	drift.Modified = w.withoutPending(drift.Modified)
+	drift.Removed = w.withoutPending(drift.Removed)
+	paths := drift.Paths()
+
+	if len(paths) > 0 {
+		w.idx.UpdateFiles(paths)
+
+		var changes []FileChange
+		for _, p := range drift.Added {
+			changes = append(changes, FileChange{Path: p, Action: "create"})
+		}
+		for _, p := range drift.Modified {
+			changes = append(changes, FileChange{Path: p, Action: "update"})
+		}
+		for _, p := range drift.Removed {
+			changes = append(changes, FileChange{Path: p, Action: "delete"})
+		}
+		log.Printf("[对账] 发现 %d 个未监听到的变更，已重新索引", len(paths))
+		w.broadcastChanges(changes)
+	}
+
+	w.updateStats(func(s *Stats) {
+		s.Scans++
+		s.LastScanAt = start.Format(time.RFC3339)
+		s.LastScanMs = time.Since(start).Milliseconds()
+		if len(paths) > 0 {
+			s.DriftFiles += len(paths)
+			s.LastDrift = paths[:min(len(paths), maxDriftSample)]
+		}
+	})
+}
+
+// withoutPending 去掉尚未处理的文件
+func (w *Watcher) withoutPending(paths []string) []string {
+	var result []string
+	for _, relPath := range paths {
+		if !w.pending(relPath) {
+			result = append(result, relPath)
+		}
+	}
+	return result
+}
+
+// pending 文件是否在当前批次中，或位于等待配对的重命名路径下
+func (w *Watcher) pending(relPath string) bool {
+	if w.batch != nil && w.batch.touched[relPath] {
+		return true
+	}
+	if w.pendingRename == "" {
+		return false
+	}
+	from := w.rel(w.pendingRename)
+	return relPath == from || strings.HasPrefix(relPath, from+"/")
+}
+
+// fullRebuild 全量重建索引，并把新索引推送给所有客户端
+func (w *Watcher) fullRebuild() {
+	log.Printf("[对账] 全量重建索引")
+	if err := w.idx.BuildIndex(); err != nil {
+		log.Printf("[索引] 构建失败: %v", err)
+	}
+
+	msg := struct {
+		Type    string `json:"type"`
+		Payload any    `json:"payload"`
+	}{Type: "index-updated", Payload: w.idx.IndexWithSources()}
+	data, _ := json.Marshal(msg)
+	w.broadcast(data)
+
+	w.updateStats(func(s *Stats) {
+		s.FullRebuilds++
+		s.LastRebuild = time.Now().Format(time.RFC3339)
+	})
+}
@@ -0,0 +1,105 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"xlxz-wiki/indexer"
+	"xlxz-wiki/ws"
+)
+
+func TestReconcile_ReindexesMissedChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "滑移.md"), []byte("旧定义\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "冲击.md"), []byte("冲击的定义\n"), 0644)
+
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	hub := ws.NewHub()
+	go hub.Run()
+
+	// 不启动监听，模拟所有事件都丢失
+	w := New(tmpDir, idx, hub)
+	os.WriteFile(filepath.Join(tmpDir, "滑移.md"), []byte("新定义\n"), 0644)
+	os.Remove(filepath.Join(tmpDir, "冲击.md"))
+	os.WriteFile(filepath.Join(tmpDir, "翻滚.md"), []byte("翻滚的定义\n"), 0644)
+
+	w.reconcile()
+
+	index := idx.GetIndex()
+	if terms := index.Terms["滑移"]; len(terms) != 1 || terms[0].Definition != "新定义" {
+		t.Errorf("修改的文件未重新索引: %+v", terms)
+	}
+	if _, ok := index.Terms["冲击"]; ok {
+		t.Error("删除的文件未移出索引")
+	}
+	if _, ok := index.Terms["翻滚"]; !ok {
+		t.Error("新增的文件未加入索引")
+	}
+
+	stats := w.Stats()
+	if stats.Scans != 1 || stats.DriftFiles != 3 || len(stats.LastDrift) != 3 {
+		t.Errorf("统计错误: %+v", stats)
+	}
+
+	// 没有漂移时不应重复计数
+	w.reconcile()
+	if stats := w.Stats(); stats.Scans != 2 || stats.DriftFiles != 3 {
+		t.Errorf("无漂移时统计错误: %+v", stats)
+	}
+}
+
+func TestFullRebuild(t *testing.T) {
+	tmpDir := t.TempDir()
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	hub := ws.NewHub()
+	go hub.Run()
+
+	w := New(tmpDir, idx, hub)
+	os.WriteFile(filepath.Join(tmpDir, "滑移.md"), []byte("定义\n"), 0644)
+
+	w.fullRebuild()
+
+	if _, ok := idx.GetIndex().Terms["滑移"]; !ok {
+		t.Error("全量重建后应包含新文件")
+	}
+	if stats := w.Stats(); stats.FullRebuilds != 1 {
+		t.Errorf("FullRebuilds = %d, want 1", stats.FullRebuilds)
+	}
+}
+
+func TestReconcile_SkipsPendingBatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	hub := ws.NewHub()
+	go hub.Run()
+
+	// 事件已收到但批次还没处理时对账：这些文件留给批次，不重复索引和广播
+	w := New(tmpDir, idx, hub)
+	os.WriteFile(filepath.Join(tmpDir, "滑移.md"), []byte("定义\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "冲击.md"), []byte("定义\n"), 0644)
+	w.record("滑移.md", "create", true)
+	w.pendingRename = filepath.Join(tmpDir, "冲击.md")
+
+	w.reconcile()
+	stopTimer(w.batchTimer)
+	if stats := w.Stats(); stats.DriftFiles != 0 {
+		t.Errorf("不应处理批次中的文件: %+v", stats)
+	}
+	if _, ok := idx.GetIndex().Terms["滑移"]; ok {
+		t.Error("对账不应索引批次中的文件")
+	}
+
+	w.clearRename()
+	w.flushBatch()
+	if _, ok := idx.GetIndex().Terms["滑移"]; !ok {
+		t.Error("批次处理后应包含新文件")
+	}
+	w.reconcile()
+	if stats := w.Stats(); stats.DriftFiles != 1 || stats.LastDrift[0] != "冲击.md" {
+		t.Errorf("不在批次中的文件应由对账处理: %+v", stats)
+	}
+}
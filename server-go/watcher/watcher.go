@@ -1,11 +1,14 @@
 package watcher
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -13,103 +16,498 @@ import (
 	"xlxz-wiki/ws"
 )
 
-// FileChangeMessage 文件变更消息
-type FileChangeMessage struct {
+const (
+	// renamePairWindow Rename 事件后等待配对 Create 事件的时间
+	// fsnotify 把一次重命名拆成旧路径的 Rename 和新路径的 Create，超时未配对则视为移出（删除）
+	renamePairWindow = 100 * time.Millisecond
+
+	// batchDelay 尾沿防抖：最后一个事件之后静默这么久才处理整批变更
+	batchDelay = 100 * time.Millisecond
+	// maxBatchDelay 持续有事件时（如 git checkout）最长等待时间，避免一直不刷新
+	maxBatchDelay = time.Second
+)
+
+// FileChange 单个文件变更
+type FileChange struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+}
+
+// FilesChangedMessage 批量文件变更消息，一批变更只广播一次
+type FilesChangedMessage struct {
 	Type    string `json:"type"`
 	Payload struct {
-		Path   string `json:"path"`
-		Action string `json:"action"`
+		Changes []FileChange `json:"changes"`
 	} `json:"payload"`
 }
 
-// Watch 监听文件变更
-func Watch(rootDir string, idx *indexer.WikiIndexer, hub *ws.Hub) {
-	watcher, err := fsnotify.NewWatcher()
+// FileMovedMessage 文件或目录移动（重命名）消息
+type FileMovedMessage struct {
+	Type    string `json:"type"`
+	Payload struct {
+		OldPath     string `json:"oldPath"`
+		Path        string `json:"path"`
+		Action      string `json:"action"`
+		IsDirectory bool   `json:"isDirectory"`
+	} `json:"payload"`
+}
+
+// Watcher 文件监听器：监听 wiki 目录变更并增量更新索引，定期对账兜底
+type Watcher struct {
+	// ReconcileInterval 定期对账扫描的间隔，0 表示不定期扫描（监听出错时仍会全量重建）
+	ReconcileInterval time.Duration
+	// OnChange 文件变更更新索引并推送给客户端后调用（如通知引用本工作区词条的其他工作区）
+	OnChange func()
+
+	rootDir string
+	fsw     *fsnotify.Watcher
+	idx     *indexer.WikiIndexer
+	hub     *ws.Hub
+
+	// dirs 已监听的目录（完整路径），删除后无法再 Stat，需要自己记录
+	dirs map[string]bool
+
+	// pendingRename 等待配对的 Rename 事件路径
+	pendingRename string
+	renameTimer   *time.Timer
+
+	// 当前批次
+	batch      *changeBatch
+	batchTimer *time.Timer
+
+	// rebuild 监听出错（如事件队列溢出）时请求全量重建
+	rebuild chan struct{}
+
+	statsMu sync.Mutex
+	stats   Stats
+}
+
+// moveEvent 一次配对后的重命名
+type moveEvent struct {
+	from, to    string
+	isDirectory bool
+}
+
+// changeBatch 防抖窗口内累积的变更
+type changeBatch struct {
+	started time.Time
+	// actions 广播用的变更动作（相对路径 → create / update / delete）
+	actions map[string]string
+	// touched 需要重新索引的文件（相对路径），处理时按磁盘上的当前状态更新或移除
+	touched map[string]bool
+	moves   []moveEvent
+}
+
+func newChangeBatch() *changeBatch {
+	return &changeBatch{
+		started: time.Now(),
+		actions: make(map[string]string),
+		touched: make(map[string]bool),
+	}
+}
+
+// New 创建监听器
+func New(rootDir string, idx *indexer.WikiIndexer, hub *ws.Hub) *Watcher {
+	return &Watcher{
+		ReconcileInterval: DefaultReconcileInterval,
+		rootDir:           rootDir,
+		idx:               idx,
+		hub:               hub,
+		dirs:              make(map[string]bool),
+		renameTimer:       stoppedTimer(),
+		batchTimer:        stoppedTimer(),
+		rebuild:           make(chan struct{}, 1),
+	}
+}
+
+// Run 开始监听文件变更（阻塞），ctx 取消后处理完当前批次再返回
+func (w *Watcher) Run(ctx context.Context) {
+	fsw, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Printf("[监听] 创建失败: %v", err)
 		return
 	}
-	defer watcher.Close()
+	defer fsw.Close()
+	w.fsw = fsw
 
 	// 添加目录监听
-	err = filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if info.IsDir() {
-			// 跳过隐藏目录（如 .annotations）
-			if path != rootDir && strings.HasPrefix(info.Name(), ".") {
-				return filepath.SkipDir
-			}
-			return watcher.Add(path)
-		}
-		return nil
-	})
-	if err != nil {
+	if err := w.addDir(w.rootDir); err != nil {
 		log.Printf("[监听] 添加目录失败: %v", err)
 		return
 	}
 
-	log.Printf("[监听] 开始监听 %s", rootDir)
-
-	// 防抖：记录最后一次事件时间
-	lastEvent := make(map[string]time.Time)
-	debounceDelay := 100 * time.Millisecond
+	log.Printf("[监听] 开始监听 %s", w.rootDir)
+	// 对账和重建与事件处理在同一个协程中进行，不会与批次交错
+	var reconcileTick <-chan time.Time
+	if w.ReconcileInterval > 0 {
+		ticker := time.NewTicker(w.ReconcileInterval)
+		defer ticker.Stop()
+		reconcileTick = ticker.C
+	}
 
 	for {
 		select {
-		case event, ok := <-watcher.Events:
+		case <-ctx.Done():
+			// 把已收到但还在防抖窗口内的变更写入索引并广播
+			w.flushRename()
+			stopTimer(w.batchTimer)
+			w.flushBatch()
+			log.Printf("[监听] 已停止")
+			return
+
+		case event, ok := <-fsw.Events:
 			if !ok {
 				return
 			}
+			w.handle(event)
 
-			// 只处理 .md 文件
-			if !strings.HasSuffix(event.Name, ".md") {
-				continue
-			}
+		case <-w.renameTimer.C:
+			w.flushRename()
 
-			// 防抖
-			now := time.Now()
-			if last, ok := lastEvent[event.Name]; ok && now.Sub(last) < debounceDelay {
-				continue
-			}
-			lastEvent[event.Name] = now
-
-			relPath, _ := filepath.Rel(rootDir, event.Name)
-			relPath = filepath.ToSlash(relPath)
-
-			var action string
-			switch {
-			case event.Op&fsnotify.Create == fsnotify.Create:
-				action = "create"
-				idx.UpdateFile(relPath)
-			case event.Op&fsnotify.Write == fsnotify.Write:
-				action = "update"
-				idx.UpdateFile(relPath)
-			case event.Op&fsnotify.Remove == fsnotify.Remove:
-				action = "delete"
-				idx.RemoveFile(relPath)
-			case event.Op&fsnotify.Rename == fsnotify.Rename:
-				action = "delete"
-				idx.RemoveFile(relPath)
-			default:
-				continue
-			}
+		case <-w.batchTimer.C:
+			w.flushBatch()
 
-			log.Printf("[监听] %s: %s", action, relPath)
+		case <-reconcileTick:
+			w.reconcile()
 
-			// 广播变更消息
-			msg := FileChangeMessage{Type: "file-changed"}
-			msg.Payload.Path = relPath
-			msg.Payload.Action = action
-			data, _ := json.Marshal(msg)
-			hub.Broadcast(data)
+		case <-w.rebuild:
+			w.fullRebuild()
 
-		case err, ok := <-watcher.Errors:
+		case err, ok := <-fsw.Errors:
 			if !ok {
 				return
 			}
-			log.Printf("[监听] 错误: %v", err)
+			w.handleError(err)
+		}
+	}
+}
+
+func (w *Watcher) handle(event fsnotify.Event) {
+	name := event.Name
+
+	switch {
+	case event.Has(fsnotify.Create):
+		if w.pendingRename != "" && renamePair(w.pendingRename, name) {
+			from := w.pendingRename
+			w.clearRename()
+			w.move(from, name)
+			return
+		}
+		// 窗口内无关的新建文件不能当作重命名目标
+		w.flushRename()
+		if isDir(name) {
+			w.createDir(name)
+			return
+		}
+		if isMarkdown(name) && !w.skip(name) {
+			w.record(w.rel(name), "create", true)
+		}
+
+	case event.Has(fsnotify.Write):
+		if isMarkdown(name) && !w.skip(name) {
+			w.record(w.rel(name), "update", true)
+		}
+
+	case event.Has(fsnotify.Remove):
+		if name == w.pendingRename {
+			w.clearRename()
+		}
+		w.remove(name)
+
+	case event.Has(fsnotify.Rename):
+		if !w.dirs[name] && !isMarkdown(name) {
+			return
+		}
+		// 目录重命名时，父目录和目录自身的监听都会报告 Rename
+		if name == w.pendingRename {
+			return
+		}
+		w.flushRename()
+		w.pendingRename = name
+		w.renameTimer.Reset(renamePairWindow)
+	}
+}
+
+// flushRename 未配对的 Rename 视为移出监听范围（删除）
+func (w *Watcher) flushRename() {
+	if w.pendingRename == "" {
+		return
+	}
+	name := w.pendingRename
+	w.clearRename()
+	w.remove(name)
+}
+
+// renamePair Create 是否可能与之前的 Rename 属于同一次重命名：
+// 移动到其他目录时文件名不变，原地重命名时所在目录不变
+func renamePair(from, to string) bool {
+	return filepath.Base(from) == filepath.Base(to) || filepath.Dir(from) == filepath.Dir(to)
+}
+
+func (w *Watcher) clearRename() {
+	w.pendingRename = ""
+	stopTimer(w.renameTimer)
+}
+
+// move 处理配对后的重命名：旧路径移出索引，新路径（目录则递归）重新索引
+func (w *Watcher) move(from, to string) {
+	if w.skip(to) {
+		// 移入隐藏或忽略的目录（不监听、不展示），等同于删除
+		w.remove(from)
+		return
+	}
+	if w.dirs[from] {
+		w.removeWatches(from)
+		if err := w.addDir(to); err != nil {
+			log.Printf("[监听] 添加目录失败: %v", err)
+		}
+		w.ensureBatch()
+		for _, relPath := range w.idx.FilesIn(w.rel(from)) {
+			w.batch.touched[relPath] = true
+		}
+		for _, relPath := range w.markdownFiles(to) {
+			w.batch.touched[relPath] = true
+		}
+		w.batch.moves = append(w.batch.moves, moveEvent{from: w.rel(from), to: w.rel(to), isDirectory: true})
+		return
+	}
+
+	switch {
+	case isDir(to):
+		// 非 Markdown 文件被重命名成目录名（极少见），按新目录处理
+		w.remove(from)
+		w.createDir(to)
+	case from == to:
+		// 编辑器先备份再写回同名文件
+		w.record(w.rel(to), "update", true)
+	case !isMarkdown(to):
+		// 重命名为非 .md 文件，等同于删除
+		w.remove(from)
+	default:
+		w.ensureBatch()
+		w.batch.touched[w.rel(from)] = true
+		w.batch.touched[w.rel(to)] = true
+		w.batch.moves = append(w.batch.moves, moveEvent{from: w.rel(from), to: w.rel(to)})
+	}
+}
+
+// createDir 新建（或移入）目录：添加监听并索引其中已有的文件
+func (w *Watcher) createDir(path string) {
+	if w.skip(path) {
+		return
+	}
+	if err := w.addDir(path); err != nil {
+		log.Printf("[监听] 添加目录失败: %v", err)
+		return
+	}
+	for _, relPath := range w.markdownFiles(path) {
+		w.record(relPath, "create", true)
+	}
+	w.record(w.rel(path), "create", false)
+}
+
+// remove 删除文件或目录（目录则递归移除监听和索引）
+func (w *Watcher) remove(path string) {
+	if w.dirs[path] {
+		w.removeWatches(path)
+		for _, relPath := range w.idx.FilesIn(w.rel(path)) {
+			w.record(relPath, "delete", true)
+		}
+		w.record(w.rel(path), "delete", false)
+		return
+	}
+	if isMarkdown(path) && !w.skip(path) {
+		w.record(w.rel(path), "delete", true)
+	}
+}
+
+// record 把变更加入当前批次，并把处理时间推迟到最后一个事件之后 batchDelay
+// reindex 为 false 时只广播不索引（目录本身的变更）
+func (w *Watcher) record(relPath, action string, reindex bool) {
+	w.ensureBatch()
+	if reindex {
+		w.batch.touched[relPath] = true
+	}
+	if merged := mergeAction(w.batch.actions[relPath], action); merged == "" {
+		delete(w.batch.actions, relPath)
+	} else {
+		w.batch.actions[relPath] = merged
+	}
+}
+
+// ensureBatch 开始新批次（如有需要）并重置尾沿定时器
+func (w *Watcher) ensureBatch() {
+	if w.batch == nil {
+		w.batch = newChangeBatch()
+	}
+	delay := min(batchDelay, time.Until(w.batch.started.Add(maxBatchDelay)))
+	stopTimer(w.batchTimer)
+	w.batchTimer.Reset(max(delay, 0))
+}
+
+// flushBatch 一次性更新整批文件的索引，然后广播一条 files-changed 消息
+func (w *Watcher) flushBatch() {
+	b := w.batch
+	w.batch = nil
+	if b == nil {
+		return
+	}
+
+	touched := make([]string, 0, len(b.touched))
+	for relPath := range b.touched {
+		touched = append(touched, relPath)
+	}
+	w.idx.UpdateFiles(touched)
+
+	if len(b.actions) > 0 {
+		changes := make([]FileChange, 0, len(b.actions))
+		for relPath, action := range b.actions {
+			changes = append(changes, FileChange{Path: relPath, Action: action})
+		}
+		log.Printf("[监听] %d 个文件变更", len(changes))
+		w.broadcastChanges(changes)
+	}
+
+	for _, m := range b.moves {
+		log.Printf("[监听] move: %s -> %s", m.from, m.to)
+		w.broadcastMove(m)
+	}
+}
+
+// mergeAction 合并同一文件在一个批次内的多次变更
+// 返回空字符串表示变更相互抵消（如临时文件先创建后删除）
+func mergeAction(prev, next string) string {
+	switch {
+	case prev == "":
+		return next
+	case prev == "create" && next == "update":
+		return "create"
+	case prev == "create" && next == "delete":
+		return ""
+	case prev == "delete" && next == "create":
+		return "update"
+	default:
+		return next
+	}
+}
+
+// markdownFiles 返回目录下（含子目录，跳过隐藏和忽略的路径）所有 .md 文件的相对路径
+func (w *Watcher) markdownFiles(root string) []string {
+	var files []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != root && w.skip(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isMarkdown(path) && !w.skip(path) {
+			files = append(files, w.rel(path))
+		}
+		return nil
+	})
+	return files
+}
+
+// addDir 递归添加目录监听，跳过隐藏目录（如 .annotations）和配置忽略的目录
+func (w *Watcher) addDir(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != w.rootDir && w.skip(path) {
+			return filepath.SkipDir
+		}
+		if err := w.fsw.Add(path); err != nil {
+			return err
+		}
+		w.dirs[path] = true
+		w.updateStats(func(s *Stats) { s.WatchedDirs = len(w.dirs) })
+		return nil
+	})
+}
+
+// removeWatches 移除目录及其子目录的监听
+func (w *Watcher) removeWatches(root string) {
+	prefix := root + string(filepath.Separator)
+	for dir := range w.dirs {
+		if dir == root || strings.HasPrefix(dir, prefix) {
+			// 目录已被删除时 fsnotify 会自动移除监听，这里忽略错误
+			w.fsw.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+	w.updateStats(func(s *Stats) { s.WatchedDirs = len(w.dirs) })
+}
+
+// skip 隐藏路径和配置忽略的路径不监听、不索引
+func (w *Watcher) skip(path string) bool {
+	return isHidden(path) || w.idx.Ignored(w.rel(path))
+}
+
+func (w *Watcher) rel(path string) string {
+	relPath, _ := filepath.Rel(w.rootDir, path)
+	return filepath.ToSlash(relPath)
+}
+
+func (w *Watcher) broadcastChanges(changes []FileChange) {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	msg := FilesChangedMessage{Type: "files-changed"}
+	msg.Payload.Changes = changes
+	data, _ := json.Marshal(msg)
+	w.broadcast(data)
+}
+
+func (w *Watcher) broadcastMove(m moveEvent) {
+	msg := FileMovedMessage{Type: "file-moved"}
+	msg.Payload.OldPath = m.from
+	msg.Payload.Path = m.to
+	msg.Payload.Action = "move"
+	msg.Payload.IsDirectory = m.isDirectory
+	data, _ := json.Marshal(msg)
+	w.broadcast(data)
+}
+
+// broadcast 推送给客户端并调用 OnChange
+func (w *Watcher) broadcast(data []byte) {
+	w.hub.Broadcast(data)
+	if w.OnChange != nil {
+		w.OnChange()
+	}
+}
+
+func isMarkdown(path string) bool {
+	return strings.HasSuffix(path, ".md")
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isHidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+// stoppedTimer 创建一个未启动的定时器
+func stoppedTimer() *time.Timer {
+	t := time.NewTimer(time.Hour)
+	t.Stop()
+	return t
+}
+
+// stopTimer 停止定时器并清空可能已到期的信号，之后可以安全 Reset
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
 		}
 	}
 }
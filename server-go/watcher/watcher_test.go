@@ -0,0 +1,251 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/websocket"
+	"xlxz-wiki/indexer"
+	"xlxz-wiki/ws"
+)
+
+// waitFor 轮询等待条件成立（文件系统事件是异步的）
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("等待超时: %s", desc)
+}
+
+// writeAtomic 先写到监听目录外再移入，保证监听到的是完整内容
+func writeAtomic(t *testing.T, path, content string) {
+	t.Helper()
+	staging := filepath.Join(t.TempDir(), filepath.Base(path))
+	if err := os.WriteFile(staging, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(staging, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hasTerm(idx *indexer.WikiIndexer, alias, filePath string) bool {
+	for _, term := range idx.GetIndex().Terms[alias] {
+		if term.FilePath == filePath {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWatch_NewDirectoryAndRename(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	hub := ws.NewHub()
+	go hub.Run()
+	go New(tmpDir, idx, hub).Run(t.Context())
+	time.Sleep(100 * time.Millisecond)
+
+	// 启动后新建的目录中的文件应被索引
+	newDir := filepath.Join(tmpDir, "新系统")
+	if err := os.MkdirAll(filepath.Join(newDir, "子目录"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	writeAtomic(t, filepath.Join(newDir, "子目录", "滑铲.md"), "滑铲的定义\n")
+	waitFor(t, "新目录中的文件被索引", func() bool { return hasTerm(idx, "滑铲", "新系统/子目录/滑铲.md") })
+
+	// 重命名文件：旧路径移除，新路径索引
+	os.Rename(filepath.Join(newDir, "子目录", "滑铲.md"), filepath.Join(newDir, "子目录", "铲击.md"))
+	waitFor(t, "文件重命名", func() bool {
+		return hasTerm(idx, "铲击", "新系统/子目录/铲击.md") && !hasTerm(idx, "滑铲", "新系统/子目录/滑铲.md")
+	})
+
+	// 重命名目录：目录下所有文件重新索引
+	os.Rename(newDir, filepath.Join(tmpDir, "旧系统"))
+	waitFor(t, "目录重命名", func() bool {
+		return hasTerm(idx, "铲击", "旧系统/子目录/铲击.md") && !hasTerm(idx, "铲击", "新系统/子目录/铲击.md")
+	})
+
+	// 重命名后的目录仍在监听
+	writeAtomic(t, filepath.Join(tmpDir, "旧系统", "子目录", "冲刺.md"), "冲刺的定义\n")
+	waitFor(t, "重命名后的目录继续监听", func() bool { return hasTerm(idx, "冲刺", "旧系统/子目录/冲刺.md") })
+
+	// 移入已有内容的目录
+	staged := filepath.Join(t.TempDir(), "外部目录")
+	os.MkdirAll(staged, 0755)
+	os.WriteFile(filepath.Join(staged, "翻滚.md"), []byte("翻滚的定义\n"), 0644)
+	if err := os.Rename(staged, filepath.Join(tmpDir, "外部目录")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "移入的目录被索引", func() bool { return hasTerm(idx, "翻滚", "外部目录/翻滚.md") })
+	os.RemoveAll(filepath.Join(tmpDir, "外部目录"))
+
+	// 递归删除目录
+	os.RemoveAll(filepath.Join(tmpDir, "旧系统"))
+	waitFor(t, "递归删除", func() bool { return len(idx.GetIndex().Terms) == 0 })
+}
+
+func TestWatch_RenamePairsOnlyPlausibleCreate(t *testing.T) {
+	tmpDir := t.TempDir()
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	w := New(tmpDir, idx, nil)
+	path := func(rel string) string { return filepath.Join(tmpDir, filepath.FromSlash(rel)) }
+
+	// 移出后窗口内恰好有无关的新建文件：旧路径删除，新文件按新建处理
+	w.handle(fsnotify.Event{Name: path("系统/滑铲.md"), Op: fsnotify.Rename})
+	w.handle(fsnotify.Event{Name: path("其他/冲刺.md"), Op: fsnotify.Create})
+	if len(w.batch.moves) != 0 {
+		t.Errorf("无关的新建不应配对为重命名: %+v", w.batch.moves)
+	}
+	if got := w.batch.actions["系统/滑铲.md"]; got != "delete" {
+		t.Errorf("旧路径动作 = %q, want delete", got)
+	}
+	if got := w.batch.actions["其他/冲刺.md"]; got != "create" {
+		t.Errorf("新文件动作 = %q, want create", got)
+	}
+
+	// 原地重命名和移动到其他目录仍然配对
+	w.batch = nil
+	w.handle(fsnotify.Event{Name: path("系统/滑铲.md"), Op: fsnotify.Rename})
+	w.handle(fsnotify.Event{Name: path("系统/铲击.md"), Op: fsnotify.Create})
+	w.handle(fsnotify.Event{Name: path("系统/铲击.md"), Op: fsnotify.Rename})
+	w.handle(fsnotify.Event{Name: path("其他/铲击.md"), Op: fsnotify.Create})
+	stopTimer(w.batchTimer)
+	want := []moveEvent{{from: "系统/滑铲.md", to: "系统/铲击.md"}, {from: "系统/铲击.md", to: "其他/铲击.md"}}
+	if fmt.Sprint(w.batch.moves) != fmt.Sprint(want) {
+		t.Errorf("moves = %+v, want %+v", w.batch.moves, want)
+	}
+}
+
+// startWatch 启动监听和一个 WebSocket 客户端，返回收到的消息通道
+func startWatch(t *testing.T, rootDir string, idx *indexer.WikiIndexer) <-chan map[string]any {
+	t.Helper()
+	hub := ws.NewHub()
+	go hub.Run()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws.ServeWs(hub, w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	messages := make(chan map[string]any, 100)
+	go func() {
+		for {
+			var msg map[string]any
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+
+	go New(rootDir, idx, hub).Run(t.Context())
+	time.Sleep(100 * time.Millisecond)
+	return messages
+}
+
+func TestWatch_TrailingEdgeKeepsLastWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	startWatch(t, tmpDir, idx)
+
+	// 模拟编辑器分步保存：先截断再写入，两次写入间隔很短
+	path := filepath.Join(tmpDir, "滑移.md")
+	os.WriteFile(path, []byte(""), 0644)
+	time.Sleep(20 * time.Millisecond)
+	os.WriteFile(path, []byte("滑移的最终定义\n"), 0644)
+
+	waitFor(t, "最后一次写入被索引", func() bool {
+		terms := idx.GetIndex().Terms["滑移"]
+		return len(terms) == 1 && terms[0].Definition == "滑移的最终定义"
+	})
+}
+
+func TestWatch_BatchesBulkChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	messages := startWatch(t, tmpDir, idx)
+
+	const n = 30
+	for i := range n {
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("词条%d.md", i)), []byte("定义\n"), 0644)
+	}
+
+	var msg map[string]any
+	select {
+	case msg = <-messages:
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到变更消息")
+	}
+	if msg["type"] != "files-changed" {
+		t.Fatalf("消息类型 = %v, want files-changed", msg["type"])
+	}
+	changes := msg["payload"].(map[string]any)["changes"].([]any)
+	if len(changes) != n {
+		t.Errorf("一批变更应包含 %d 个文件，实际 %d", n, len(changes))
+	}
+	if len(idx.GetIndex().Terms) != n {
+		t.Errorf("广播前应完成索引，实际 %d 个词条", len(idx.GetIndex().Terms))
+	}
+
+	select {
+	case extra := <-messages:
+		t.Errorf("一批变更只应广播一次，多余消息: %v", extra)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatch_StopFlushesPendingBatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	idx := indexer.New(tmpDir)
+	idx.BuildIndex()
+	hub := ws.NewHub()
+	go hub.Run()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		New(tmpDir, idx, hub).Run(ctx)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// 事件已收到但还在防抖窗口内时停止
+	os.WriteFile(filepath.Join(tmpDir, "滑移.md"), []byte("定义\n"), 0644)
+	time.Sleep(batchDelay / 3)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("取消后 Run 未返回")
+	}
+	if !hasTerm(idx, "滑移", "滑移.md") {
+		t.Error("停止前应处理完当前批次")
+	}
+}
@@ -1,19 +1,17 @@
 package ws
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // 允许所有来源
-	},
-}
-
 // Client WebSocket 客户端
 type Client struct {
 	hub  *Hub
@@ -28,22 +26,77 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	upgrader       websocket.Upgrader
+	allowedOrigins map[string]bool
+
+	// quit 关闭后 Run 退出，所有连接在发送完已排队的消息后收到 close 帧
+	quit      chan struct{}
+	closeOnce sync.Once
+	// closed 与 pumps.Add 在 mu 下互斥，保证 Close 开始等待后不再有新连接
+	closed bool
+	// pumps 仍在发送消息的连接
+	pumps sync.WaitGroup
 }
 
+// writeWait 发送 close 帧的超时
+const writeWait = 5 * time.Second
+
 // NewHub 创建 Hub
 func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+	h := &Hub{
+		clients:        make(map[*Client]bool),
+		broadcast:      make(chan []byte),
+		register:       make(chan *Client),
+		unregister:     make(chan *Client),
+		allowedOrigins: make(map[string]bool),
+		quit:           make(chan struct{}),
 	}
+	h.upgrader.CheckOrigin = h.checkOrigin
+	return h
 }
 
-// Run 运行 Hub
+// AllowOrigins 设置除同源外允许建立连接的来源（scheme://host[:port]），* 表示允许所有来源
+// 需在开始接受连接前调用
+func (h *Hub) AllowOrigins(origins []string) {
+	for _, origin := range origins {
+		h.allowedOrigins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+}
+
+// checkOrigin 允许同源请求、非浏览器客户端（无 Origin）和配置的来源
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || h.allowedOrigins["*"] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if h.allowedOrigins[strings.ToLower(u.Scheme+"://"+u.Host)] {
+		return true
+	}
+	log.Printf("[WebSocket] 拒绝来源 %s", origin)
+	return false
+}
+
+// Run 运行 Hub，Close 后返回
 func (h *Hub) Run() {
 	for {
 		select {
+		case <-h.quit:
+			h.mu.Lock()
+			for client := range h.clients {
+				delete(h.clients, client)
+				close(client.send)
+			}
+			h.mu.Unlock()
+			return
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -74,14 +127,40 @@ func (h *Hub) Run() {
 	}
 }
 
-// Broadcast 广播消息
+// Broadcast 广播消息，Hub 关闭后丢弃
 func (h *Hub) Broadcast(message []byte) {
-	h.broadcast <- message
+	select {
+	case h.broadcast <- message:
+	case <-h.quit:
+	}
+}
+
+// Close 关闭 Hub：每个连接发送完已排队的消息后收到 close 帧（1001 Going Away）
+// 在 ctx 到期前等待所有连接发送完毕
+func (h *Hub) Close(ctx context.Context) error {
+	h.closeOnce.Do(func() {
+		h.mu.Lock()
+		h.closed = true
+		h.mu.Unlock()
+		close(h.quit)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // ServeWs 处理 WebSocket 连接
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := hub.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("[WebSocket] 升级失败: %v", err)
 		return
@@ -92,15 +171,41 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		conn: conn,
 		send: make(chan []byte, 256),
 	}
-	hub.register <- client
+	hub.mu.Lock()
+	if hub.closed {
+		hub.mu.Unlock()
+		client.reject()
+		return
+	}
+	hub.pumps.Add(1)
+	hub.mu.Unlock()
+
+	select {
+	case hub.register <- client:
+	case <-hub.quit:
+		hub.pumps.Done()
+		client.reject()
+		return
+	}
 
 	go client.writePump()
 	go client.readPump()
 }
 
+var closeGoingAway = websocket.FormatCloseMessage(websocket.CloseGoingAway, "服务器关闭")
+
+// reject Hub 已关闭时拒绝新连接
+func (c *Client) reject() {
+	c.conn.WriteControl(websocket.CloseMessage, closeGoingAway, time.Now().Add(writeWait))
+	c.conn.Close()
+}
+
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.quit:
+		}
 		c.conn.Close()
 	}()
 
@@ -114,12 +219,15 @@ func (c *Client) readPump() {
 	}
 }
 
+// writePump 发送消息；send 被关闭（Hub 关闭、连接断开或发送过慢）时发送 close 帧并断开
 func (c *Client) writePump() {
+	defer c.hub.pumps.Done()
 	defer c.conn.Close()
 
 	for message := range c.send {
 		if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			break
+			return
 		}
 	}
+	c.conn.WriteControl(websocket.CloseMessage, closeGoingAway, time.Now().Add(writeWait))
 }
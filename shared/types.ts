@@ -4,18 +4,38 @@ export interface WikiTerm {
   term: string
   /** 所有别名（包含 term 本身） */
   aliases: string[]
-  /** 定义内容（Markdown 格式） */
+  /** 定义内容（Markdown 格式，由完整的段落、表格、代码块组成） */
   definition: string
+  /** 定义的纯文本，配置了 features.definitionLimit 时截断 */
+  definitionText?: string
   /** 作用域（空字符串表示全局） */
   scope: string
   /** 来源文件路径（相对于 wiki-docs/） */
   filePath: string
   /** 定义类型 */
-  definitionType: 'file' | 'inline'
+  definitionType: 'file' | 'inline' | 'section'
   /** 定义所在行号（原始文件中的行号，1-based） */
   line?: number
-  /** 是否有更多内容（文件定义模式下，存在 <!-- more --> 标记时为 true） */
+  /** 章节定义：章节标题 */
+  section?: string
+  /** 章节定义：标题锚点 */
+  anchor?: string
+  /** 章节定义：章节最后一行 */
+  endLine?: number
+  /** 是否有更多内容（文件定义模式下，存在 <!-- more --> 标记、frontmatter summary 或超过字数上限时为 true） */
   hasMore?: boolean
+  /** 所在文档 frontmatter 中的标签 */
+  tags?: string[]
+  /** 所在文档的状态，未设置时为空 */
+  status?: DocStatusName
+  /** 文件词条已废弃（frontmatter deprecated: true） */
+  deprecated?: boolean
+  /** 替代的词条名（frontmatter replacedBy） */
+  replacedBy?: string
+  /** 禁止使用的同义说法（frontmatter synonyms-forbidden） */
+  forbiddenSynonyms?: string[]
+  /** 来自其他工作区时为该工作区名称（只读，【工作区:词条】 引用） */
+  workspace?: string
 }
 
 /** 策划公式 */
@@ -44,13 +64,23 @@ export interface WikiIndex {
   scopes: string[]
   /** 索引构建时间 */
   buildTime: number
+  /** 其他工作区的词条：工作区名称 → alias → WikiTerm[] */
+  external?: Record<string, Record<string, WikiTerm[]>>
+}
+
+/** 单个文件变更（同一批次内的多次变更已合并） */
+export interface FileChange {
+  path: string
+  action: 'create' | 'update' | 'delete'
 }
 
 /** WebSocket 消息 */
 export type WsMessage =
-  | { type: 'file-changed'; payload: { path: string; action: 'create' | 'update' | 'delete' } }
+  | { type: 'files-changed'; payload: { changes: FileChange[] } }
+  | { type: 'file-moved'; payload: { oldPath: string; path: string; action: 'move'; isDirectory: boolean } }
   | { type: 'index-updated'; payload: WikiIndex }
   | { type: 'refresh-index' }
+  | { type: 'external-updated'; payload: { workspace: string } }
 
 /** 文件树节点 */
 export interface FileTreeNode {
@@ -60,6 +90,52 @@ export interface FileTreeNode {
   children?: FileTreeNode[]
   /** 当存在同名文件夹和同名 .md 文件时，该字段记录被合并的 .md 文件路径 */
   readmePath?: string
+  /** 文档 frontmatter 中的标签（目录为 readmePath 的标签） */
+  tags?: string[]
+}
+
+/** 文档状态：草稿 → 审核中 → 已定稿 → 已废弃 */
+export type DocStatusName = 'draft' | 'review' | 'approved' | 'deprecated'
+
+/** 文档的状态和可以变更到的状态（/api/file/status），status 为空表示未设置 */
+export interface DocStatus {
+  status: DocStatusName | ''
+  next: DocStatusName[]
+}
+
+/** 标签及带有该标签的文档（/api/tags） */
+export interface TagInfo {
+  tag: string
+  /** 文档数 */
+  count: number
+  /** 文档路径 */
+  documents: string[]
+}
+
+/** 正文中未加【】的词条（/api/links，features.autoLink） */
+export interface AutoLink {
+  /** 所在行号（1-based） */
+  line: number
+  /** 所在列（按字符计，1-based） */
+  column: number
+  /** 原文 */
+  text: string
+  /** 链接到的词条 */
+  term: string
+}
+
+/** 文档大纲中的标题（/api/outline） */
+export interface OutlineItem {
+  /** 标题级别 1-6 */
+  level: number
+  /** 标题纯文本 */
+  text: string
+  /** 锚点，与【词条#锚点】和渲染结果中的 id 一致 */
+  slug: string
+  /** 标题所在行号（1-based） */
+  line: number
+  /** 下级标题 */
+  children?: OutlineItem[]
 }
 
 /** ─── 审校批注 ─────────────────────────────────────────── */
@@ -84,6 +160,12 @@ export interface Annotation {
   createdAt: string
   /** 更新时间（ISO 8601） */
   updatedAt: string
+  /** 建议的替换文本（可通过 /api/annotations/apply 直接采纳），空字符串表示建议删除原文 */
+  suggestion?: string
+  /** 采纳建议的人 */
+  appliedBy?: string
+  /** 采纳时间（ISO 8601） */
+  appliedAt?: string
 }
 
 /** 单个文档的批注集合（持久化 JSON 格式） */
@@ -95,3 +177,22 @@ export interface AnnotationFile {
   /** 批注列表 */
   annotations: Annotation[]
 }
+
+// ─── 访问控制 ────────────────────────────────────────────────
+
+/** 访问角色：viewer 只读，reviewer 可审校批注，editor 可编辑文档 */
+export type Role = 'none' | 'viewer' | 'reviewer' | 'editor'
+
+/** 当前访问者（/api/version 返回），匿名访问时 name 为空 */
+export interface CurrentUser {
+  name: string
+  role: Role
+}
+
+/** 工作区（/api/workspaces 返回），url 为该工作区页面的访问路径 */
+export interface WorkspaceInfo {
+  name: string
+  url: string
+  fileCount: number
+  termCount: number
+}
@@ -8,6 +8,9 @@ import { wikiTermRule } from './rules/wiki-term'
 import { wikiDefinitionRule } from './rules/wiki-definition'
 import { wikiFormulaRule } from './rules/wiki-formula'
 import { mermaidRule } from './rules/mermaid'
+import { headingAttrsRule } from './rules/heading-attrs'
+import { autoLinkRule } from './rules/auto-link'
+import type { AutoLink } from '@shared/types'
 
 /**
  * 给块级元素注入 data-line 属性的插件
@@ -37,8 +40,9 @@ function injectLineNumbers(md: MarkdownIt, lineOffset: number): void {
 /**
  * 创建配置好的 markdown-it 实例
  * @param lineOffset frontmatter 行数偏移（默认 0）
+ * @param links 自动链接的位置（/api/links），为空时不自动链接
  */
-export function createMarkdownRenderer(lineOffset: number = 0): MarkdownIt {
+export function createMarkdownRenderer(lineOffset: number = 0, links: AutoLink[] = []): MarkdownIt {
   const md = new MarkdownIt({
     html: true,
     linkify: true,
@@ -56,6 +60,8 @@ export function createMarkdownRenderer(lineOffset: number = 0): MarkdownIt {
   wikiDefinitionRule(md)
   wikiTermRule(md)
   mermaidRule(md)
+  headingAttrsRule(md)
+  autoLinkRule(md, links, lineOffset)
 
   return md
 }
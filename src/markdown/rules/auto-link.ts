@@ -0,0 +1,74 @@
+/**
+ * markdown-it 规则：自动链接（features.autoLink）
+ *
+ * 服务端 /api/links 返回正文中未加【】的词条位置（已排除代码、公式、标题、链接和 HTML 标签），
+ * 按行号找到所在段落和行，将对应文本替换为 <wiki-term auto>，悬停时与【词条】相同
+ */
+import type MarkdownIt from 'markdown-it'
+import type { AutoLink } from '@shared/types'
+
+/** 已有的【词条】和 %%公式%% 中的文本不链接，与服务端相同 */
+const maskRe = /【[^】]*】|%%.*?%%/g
+
+/** HTML 属性转义 */
+function escapeAttr(str: string): string {
+  return str.replace(/&/g, '&amp;').replace(/"/g, '&quot;').replace(/</g, '&lt;').replace(/>/g, '&gt;')
+}
+
+/**
+ * @param links 服务端返回的链接位置
+ * @param lineOffset frontmatter 行数偏移，与 data-line 相同
+ */
+export function autoLinkRule(md: MarkdownIt, links: AutoLink[], lineOffset: number): void {
+  if (links.length === 0) return
+  const sorted = [...links].sort((a, b) => a.line - b.line || a.column - b.column)
+
+  md.core.ruler.push('auto_link', (state) => {
+    for (const inline of state.tokens) {
+      if (inline.type !== 'inline' || !inline.map || !inline.children) continue
+      // map 为正文的 0-based 行范围 [start, end)，换算成原始文件的 1-based 行号
+      const first = inline.map[0] + lineOffset + 1
+      const last = inline.map[1] + lineOffset
+      const pending = sorted.filter(l => l.line >= first && l.line <= last)
+      if (pending.length === 0) continue
+
+      const children: typeof inline.children = []
+      // 按换行跟踪文本所在的行，只匹配该行的链接；链接中的文本不再嵌套词条，与服务端一致
+      let line = first
+      let inLink = 0
+      for (const child of inline.children) {
+        if (child.type === 'softbreak' || child.type === 'hardbreak') line++
+        else if (child.type === 'link_open') inLink++
+        else if (child.type === 'link_close') inLink--
+        if (child.type !== 'text' || inLink > 0) {
+          children.push(child)
+          continue
+        }
+        // 按列的顺序在文本中依次查找，本节点找不到的留给同一行后面的文本节点
+        let rest = child.content
+        while (true) {
+          const i = pending.findIndex(l => l.line === line)
+          if (i < 0) break
+          const masked = rest.replace(maskRe, m => '\0'.repeat(m.length))
+          const at = masked.indexOf(pending[i].text)
+          if (at < 0) break
+          const [link] = pending.splice(i, 1)
+          if (at > 0) {
+            const text = new state.Token('text', '', 0)
+            text.content = rest.slice(0, at)
+            children.push(text)
+          }
+          const term = new state.Token('html_inline', '', 0)
+          term.content = `<wiki-term term="${escapeAttr(link.term)}" auto></wiki-term>`
+          children.push(term)
+          rest = rest.slice(at + link.text.length)
+        }
+        if (rest) {
+          child.content = rest
+          children.push(child)
+        }
+      }
+      inline.children = children
+    }
+  })
+}
@@ -0,0 +1,52 @@
+/**
+ * markdown-it 规则：标题锚点
+ *
+ * 语法：
+ * ## 判定规则 {#rules .term}
+ *
+ * 拆出标题末尾的属性，给顶层标题加上 id 锚点。
+ * {#锚点} 指定锚点，否则由标题生成（与服务端 markdown.Slug 一致），重复的依次加 -1、-2 后缀；
+ * {.term} 标记的标题由服务端索引为词条，这里只需去掉属性
+ */
+import type MarkdownIt from 'markdown-it'
+
+const attrRe = /[ \t]*\{[ \t]*([#.][^\s{}]+(?:[ \t]+[#.][^\s{}]+)*)[ \t]*\}$/
+
+/** 由标题生成锚点：保留字母、数字、- 和 _，空白替换为 -，英文转小写 */
+export function slugify(text: string): string {
+  return text.trim().toLowerCase().replace(/[^\p{L}\p{N}\s_-]/gu, '').replace(/\s/gu, '-')
+}
+
+export function headingAttrsRule(md: MarkdownIt): void {
+  md.core.ruler.push('heading_attrs', (state) => {
+    const used = new Map<string, number>()
+    const tokens = state.tokens
+
+    for (let i = 0; i + 1 < tokens.length; i++) {
+      const open = tokens[i]
+      const inline = tokens[i + 1]
+      if (open.type !== 'heading_open' || open.level !== 0 || inline.type !== 'inline') continue
+
+      let id = ''
+      const children = inline.children ?? []
+      const last = children[children.length - 1]
+      const m = last?.type === 'text' ? attrRe.exec(last.content) : null
+      if (m) {
+        last.content = last.content.slice(0, m.index)
+        inline.content = inline.content.replace(attrRe, '')
+        for (const attr of m[1].split(/\s+/)) {
+          if (attr.startsWith('#')) id = attr.slice(1)
+        }
+      }
+
+      let slug = id || slugify(children.map(t => t.content).join('')) || 'section'
+      const n = used.get(slug)
+      if (n !== undefined) {
+        used.set(slug, n + 1)
+        slug = `${slug}-${n + 1}`
+      }
+      used.set(slug, 0)
+      open.attrSet('id', slug)
+    }
+  })
+}
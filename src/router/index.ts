@@ -1,7 +1,9 @@
-import { createRouter, createWebHistory } from 'vue-router'
+import { createRouter, createWebHashHistory, createWebHistory } from 'vue-router'
+import { basePath, staticMode } from '@/services/api'
 
 const router = createRouter({
-  history: createWebHistory(),
+  // 静态导出的站点没有服务端回退到 index.html，使用 hash 路由
+  history: staticMode ? createWebHashHistory() : createWebHistory(basePath),
   routes: [
     {
       path: '/',
@@ -0,0 +1,51 @@
+/**
+ * 服务地址
+ *
+ * 服务端在 index.html 中注入 <base href>（部署在反向代理子路径下时如 /wiki/），
+ * API、WebSocket 和前端路由都基于它拼接；开发模式下没有 <base>，使用 /。
+ *
+ * `xlxz-wiki export` 导出的静态站点带有 <meta name="xlxz-wiki-static">：
+ * 没有后端，API 换成同目录下 data/ 中的 JSON 和文档原文，路由使用 hash 模式。
+ * 单文件导出（-single）把 data/ 下的文件内嵌在页面中，file:// 下无法 fetch，由 fetch 拦截提供。
+ */
+
+/** 对外访问路径前缀，以 / 开头和结尾 */
+export const basePath = document.querySelector('base')?.getAttribute('href') ?? '/'
+
+/** 是否为静态导出的站点 */
+export const staticMode = document.querySelector('meta[name="xlxz-wiki-static"]') !== null
+
+/** 拼接 API 地址：apiUrl('/api/index') → /wiki/api/index，静态站点中为 data/index.json */
+export function apiUrl(path: string): string {
+  if (staticMode) return staticUrl(path)
+  return basePath + path.replace(/^\//, '')
+}
+
+/** 静态站点中 API 对应的文件，相对于页面地址；没有对应文件的 API 返回 404 */
+function staticUrl(path: string): string {
+  const url = new URL(path, 'http://static')
+  if (url.pathname === '/api/file') {
+    const file = url.searchParams.get('path') ?? ''
+    return 'data/docs/' + file.split('/').map(encodeURIComponent).join('/')
+  }
+  return 'data/' + url.pathname.replace(/^\/api\//, '') + '.json'
+}
+
+/** 单文件导出内嵌的 data/ 文件：路径 → 内容 */
+const bundled: Record<string, string> | null = (() => {
+  const el = document.getElementById('xlxz-wiki-data')
+  return el?.textContent ? JSON.parse(el.textContent) : null
+})()
+
+if (bundled) {
+  const originalFetch = window.fetch.bind(window)
+  window.fetch = (input, init) => {
+    if (typeof input === 'string' && input.startsWith('data/')) {
+      const body = bundled[decodeURIComponent(input)]
+      return Promise.resolve(
+        body === undefined ? new Response(null, { status: 404 }) : new Response(body),
+      )
+    }
+    return originalFetch(input, init)
+  }
+}
@@ -3,6 +3,8 @@
  * 连接后端 WebSocket，接收文件变更和索引更新推送
  */
 import { useWikiStore } from '@/stores/wiki'
+import router from '@/router'
+import { apiUrl } from '@/services/api'
 import type { WsMessage } from '@shared/types'
 
 let ws: WebSocket | null = null
@@ -25,7 +27,7 @@ export function initWebSocket(): void {
   }
 
   const protocol = location.protocol === 'https:' ? 'wss:' : 'ws:'
-  const url = `${protocol}//${location.host}/ws`
+  const url = `${protocol}//${location.host}${apiUrl('/ws')}`
 
   console.log('[WS] 正在连接:', url)
   ws = new WebSocket(url)
@@ -63,25 +65,50 @@ function handleMessage(msg: WsMessage): void {
   const store = useWikiStore()
 
   switch (msg.type) {
-    case 'file-changed': {
-      const { path, action } = msg.payload
-      console.log(`[WS] 文件${action === 'create' ? '新增' : action === 'delete' ? '删除' : '变更'}: ${path}`)
+    case 'files-changed': {
+      const { changes } = msg.payload
+      console.log(`[WS] ${changes.length} 个文件变更`)
 
-      // 如果当前正在查看该文件且文件被更新，重新加载
-      if (action === 'update' && store.currentFile === path) {
-        store.loadFile(path)
+      // 如果当前正在查看的文件被更新，重新加载
+      if (changes.some(c => c.action === 'update' && c.path === store.currentFile)) {
+        store.loadFile(store.currentFile)
       }
 
-      // 文件新增或删除时，刷新文件树
-      if (action === 'create' || action === 'delete') {
+      // 有文件新增或删除时，刷新文件树（整批只刷新一次）
+      if (changes.some(c => c.action === 'create' || c.action === 'delete')) {
         store.fetchFileTree()
       }
       break
     }
 
+    case 'file-moved': {
+      const { oldPath, path, isDirectory } = msg.payload
+      console.log(`[WS] ${isDirectory ? '目录' : '文件'}移动: ${oldPath} → ${path}`)
+
+      store.fetchFileTree()
+      store.fetchIndex()
+
+      // 当前查看的文件被移动（或位于被移动的目录中）时，跳转到新路径
+      const current = store.currentFile
+      if (current === oldPath || (isDirectory && current.startsWith(oldPath + '/'))) {
+        router.replace(`/doc/${path + current.slice(oldPath.length)}`)
+      }
+      break
+    }
+
+    case 'external-updated': {
+      // 其他工作区的词条变化，【工作区:词条】 引用需要新的定义
+      console.log(`[WS] 工作区 ${msg.payload.workspace} 的词条已更新`)
+      store.fetchIndex()
+      break
+    }
+
     case 'index-updated': {
+      // 服务端全量重建索引（如监听丢失事件后），文件树和当前文档也可能已变化
       console.log('[WS] 索引已更新')
       store.updateIndex(msg.payload)
+      store.fetchFileTree()
+      if (store.currentFile) store.loadFile(store.currentFile)
       break
     }
   }
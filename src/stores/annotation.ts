@@ -7,6 +7,8 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
 import type { Annotation, AnnotationFile } from '@shared/types'
+import { useWikiStore } from '@/stores/wiki'
+import { apiUrl } from '@/services/api'
 
 export const useAnnotationStore = defineStore('annotation', () => {
   // ─── 状态 ─────────────────────────────────────────────────
@@ -39,7 +41,7 @@ export const useAnnotationStore = defineStore('annotation', () => {
     currentFilePath.value = filePath
     loading.value = true
     try {
-      const res = await fetch(`/api/annotations?path=${encodeURIComponent(filePath)}`)
+      const res = await fetch(apiUrl(`/api/annotations?path=${encodeURIComponent(filePath)}`))
       if (res.ok) {
         const data: AnnotationFile = await res.json()
         annotations.value = data.annotations
@@ -65,6 +67,7 @@ export const useAnnotationStore = defineStore('annotation', () => {
     startOffset: number,
     endOffset: number,
     anchorLine: number | null,
+    suggestion?: string,
   ) {
     const now = new Date().toISOString()
     const annotation: Annotation = {
@@ -78,6 +81,7 @@ export const useAnnotationStore = defineStore('annotation', () => {
       createdAt: now,
       updatedAt: now,
     }
+    if (suggestion !== undefined) annotation.suggestion = suggestion
     annotations.value.push(annotation)
     await saveAnnotations()
     return annotation
@@ -93,6 +97,34 @@ export const useAnnotationStore = defineStore('annotation', () => {
     await saveAnnotations()
   }
 
+  /**
+   * 采纳建议修改
+   * 后端替换原文并写回文档，批注标记为已解决；文档变更由 WebSocket 推送刷新
+   */
+  async function applySuggestion(id: string): Promise<boolean> {
+    if (!currentFilePath.value) return false
+    const params = new URLSearchParams({ path: currentFilePath.value, id })
+    try {
+      const res = await fetch(apiUrl(`/api/annotations/apply?${params}`), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        // 登录用户由服务端按账号记录，匿名访问时才询问名字
+        body: JSON.stringify({ appliedBy: useWikiStore().user.name || getReviewerName() }),
+      })
+      if (!res.ok) {
+        console.error('[Annotation] 采纳建议失败:', await res.text())
+        return false
+      }
+      const applied: Annotation = await res.json()
+      const index = annotations.value.findIndex(a => a.id === id)
+      if (index !== -1) annotations.value[index] = applied
+      return true
+    } catch (err) {
+      console.error('[Annotation] 采纳建议失败:', err)
+      return false
+    }
+  }
+
   /** 删除批注 */
   async function removeAnnotation(id: string) {
     annotations.value = annotations.value.filter(a => a.id !== id)
@@ -108,7 +140,7 @@ export const useAnnotationStore = defineStore('annotation', () => {
       annotations: annotations.value,
     }
     try {
-      await fetch(`/api/annotations?path=${encodeURIComponent(currentFilePath.value)}`, {
+      await fetch(apiUrl(`/api/annotations?path=${encodeURIComponent(currentFilePath.value)}`), {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(data),
@@ -136,11 +168,23 @@ export const useAnnotationStore = defineStore('annotation', () => {
     loadAnnotations,
     addAnnotation,
     updateAnnotation,
+    applySuggestion,
     removeAnnotation,
     clear,
   }
 })
 
+/** 审校者名称（首次采纳建议时询问，保存在 localStorage） */
+function getReviewerName(): string {
+  const key = 'xlxz-wiki:reviewer'
+  let name = localStorage.getItem(key) || ''
+  if (!name) {
+    name = window.prompt('请输入你的名字（记录采纳人）')?.trim() || ''
+    if (name) localStorage.setItem(key, name)
+  }
+  return name
+}
+
 /** 生成短随机 ID */
 function generateId(): string {
   return Date.now().toString(36) + Math.random().toString(36).slice(2, 8)
@@ -1,6 +1,7 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import type { WikiIndex, FileTreeNode } from '@shared/types'
+import type { WikiIndex, FileTreeNode, CurrentUser, WorkspaceInfo, DocStatus, DocStatusName, AutoLink } from '@shared/types'
+import { apiUrl } from '@/services/api'
 
 export const useWikiStore = defineStore('wiki', () => {
   // ─── 状态 ─────────────────────────────────────────────────
@@ -16,6 +17,9 @@ export const useWikiStore = defineStore('wiki', () => {
   /** 文件树 */
   const fileTree = ref<FileTreeNode[]>([])
 
+  /** 文件树的标签筛选：只显示带有全部这些标签的文档 */
+  const activeTags = ref<string[]>([])
+
   /** 当前查看的文件路径（相对于 wiki-docs/） */
   const currentFile = ref('')
 
@@ -25,6 +29,12 @@ export const useWikiStore = defineStore('wiki', () => {
   /** 当前作用域 */
   const currentScope = ref('')
 
+  /** 当前文件中未加【】的词条位置，仅在服务器开启自动链接时获取 */
+  const autoLinks = ref<AutoLink[]>([])
+
+  /** 当前文件的状态和可以变更到的状态 */
+  const docStatus = ref<DocStatus>({ status: '', next: [] })
+
   /** 模式：只读 / 编辑 */
   const mode = ref<'readonly' | 'edit' | 'review'>('readonly')
 
@@ -43,6 +53,21 @@ export const useWikiStore = defineStore('wiki', () => {
   /** 后端版本号（运行时从 API 获取） */
   const backendVersion = ref('')
 
+  /** 当前访问者（运行时从 API 获取），未获取前按编辑者处理 */
+  const user = ref<CurrentUser>({ name: '', role: 'editor' })
+
+  /** 服务器是否为只读实例（运行时从 API 获取） */
+  const readOnly = ref(false)
+
+  /** 服务器是否开启了自动链接（features.autoLink） */
+  const autoLink = ref(false)
+
+  /** 服务器上的工作区，第一个为默认工作区 */
+  const workspaces = ref<WorkspaceInfo[]>([])
+
+  /** 当前页面所属的工作区 */
+  const currentWorkspace = ref('')
+
   /** 版本不匹配警告 */
   const versionMismatch = computed(() => {
     if (!backendVersion.value) return false
@@ -58,12 +83,54 @@ export const useWikiStore = defineStore('wiki', () => {
     return parts[parts.length - 1]
   })
 
+  /** 可以添加审校批注（只读实例上不可用） */
+  const canReview = computed(() =>
+    !readOnly.value && (user.value.role === 'reviewer' || user.value.role === 'editor'),
+  )
+
+  /** 可以编辑文档（包括采纳建议修改，只读实例上不可用） */
+  const canEdit = computed(() => !readOnly.value && user.value.role === 'editor')
+
+  /** 文件树中的标签及文档数，按文档数从多到少排序 */
+  const tagCounts = computed(() => {
+    const counts = new Map<string, number>()
+    const walk = (nodes: FileTreeNode[]) => {
+      for (const node of nodes) {
+        for (const tag of node.tags ?? []) counts.set(tag, (counts.get(tag) ?? 0) + 1)
+        if (node.children) walk(node.children)
+      }
+    }
+    walk(fileTree.value)
+    return [...counts].map(([tag, count]) => ({ tag, count }))
+      .sort((a, b) => b.count - a.count || a.tag.localeCompare(b.tag))
+  })
+
+  /** 按 activeTags 筛选后的文件树，保留包含匹配文档的目录 */
+  const filteredFileTree = computed(() => {
+    const want = activeTags.value
+    if (want.length === 0) return fileTree.value
+    const filter = (nodes: FileTreeNode[]): FileTreeNode[] => nodes.flatMap((node) => {
+      const matched = want.every(tag => node.tags?.includes(tag))
+      if (!node.isDirectory) return matched ? [node] : []
+      const children = filter(node.children ?? [])
+      return matched || children.length > 0 ? [{ ...node, children }] : []
+    })
+    return filter(fileTree.value)
+  })
+
   // ─── 操作 ─────────────────────────────────────────────────
 
+  /** 切换文件树的标签筛选 */
+  function toggleTag(tag: string) {
+    const i = activeTags.value.indexOf(tag)
+    if (i >= 0) activeTags.value.splice(i, 1)
+    else activeTags.value.push(tag)
+  }
+
   /** 从后端获取索引 */
   async function fetchIndex() {
     try {
-      const res = await fetch('/api/index')
+      const res = await fetch(apiUrl('/api/index'))
       index.value = await res.json()
     } catch (err) {
       console.error('[Store] 获取索引失败:', err)
@@ -73,7 +140,7 @@ export const useWikiStore = defineStore('wiki', () => {
   /** 从后端获取文件树 */
   async function fetchFileTree() {
     try {
-      const res = await fetch('/api/files')
+      const res = await fetch(apiUrl('/api/files'))
       fileTree.value = (await res.json()) ?? []
     } catch (err) {
       console.error('[Store] 获取文件树失败:', err)
@@ -83,9 +150,13 @@ export const useWikiStore = defineStore('wiki', () => {
   /** 检查后端版本号 */
   async function checkVersion() {
     try {
-      const res = await fetch('/api/version')
+      const res = await fetch(apiUrl('/api/version'))
       const data = await res.json()
       backendVersion.value = data.version
+      if (data.user) user.value = data.user
+      readOnly.value = Boolean(data.readOnly)
+      autoLink.value = Boolean(data.autoLink)
+      if (readOnly.value && mode.value !== 'readonly') mode.value = 'readonly'
       if (frontendVersion.value !== data.version) {
         console.warn(
           `[Store] 版本不匹配！前端: v${frontendVersion.value}, 后端: v${data.version}`,
@@ -96,18 +167,39 @@ export const useWikiStore = defineStore('wiki', () => {
     }
   }
 
+  /** 获取工作区列表 */
+  async function fetchWorkspaces() {
+    try {
+      const res = await fetch(apiUrl('/api/workspaces'))
+      const data = await res.json()
+      workspaces.value = data.workspaces ?? []
+      currentWorkspace.value = data.current ?? ''
+    } catch (err) {
+      console.error('[Store] 获取工作区失败:', err)
+    }
+  }
+
+  /** 切换工作区：打开该工作区的页面，索引、文件树和实时推送都随之切换 */
+  function switchWorkspace(name: string) {
+    const target = workspaces.value.find((w) => w.name === name)
+    if (target && name !== currentWorkspace.value) window.location.assign(target.url)
+  }
+
   /** 加载指定文件的内容 */
   async function loadFile(filePath: string) {
     if (!filePath) return
     loading.value = true
     currentFile.value = filePath
     try {
-      const res = await fetch(`/api/file?path=${encodeURIComponent(filePath)}`)
+      const res = await fetch(apiUrl(`/api/file?path=${encodeURIComponent(filePath)}`))
       if (res.ok) {
         const text = await res.text()
+        // 与内容一起更新，避免新内容按旧位置链接
+        autoLinks.value = autoLink.value ? await fetchAutoLinks(filePath) : []
         currentContent.value = text
         // 从 frontmatter 提取 scope
         currentScope.value = extractScope(text)
+        fetchDocStatus()
       } else {
         currentContent.value = `> 加载失败: ${filePath}`
         currentScope.value = ''
@@ -121,6 +213,46 @@ export const useWikiStore = defineStore('wiki', () => {
     }
   }
 
+  /** 获取文件中未加【】的词条位置，失败时不自动链接 */
+  async function fetchAutoLinks(filePath: string): Promise<AutoLink[]> {
+    try {
+      const res = await fetch(apiUrl(`/api/links?path=${encodeURIComponent(filePath)}`))
+      if (res.ok) return (await res.json()) ?? []
+    } catch (err) {
+      console.error('[Store] 获取自动链接失败:', err)
+    }
+    return []
+  }
+
+  /** 获取当前文件的状态 */
+  async function fetchDocStatus() {
+    docStatus.value = { status: '', next: [] }
+    try {
+      const res = await fetch(apiUrl(`/api/file/status?path=${encodeURIComponent(currentFile.value)}`))
+      if (res.ok) docStatus.value = await res.json()
+    } catch (err) {
+      console.error('[Store] 获取文档状态失败:', err)
+    }
+  }
+
+  /** 变更当前文件的状态（写入 frontmatter，文件变更通知会刷新内容和索引） */
+  async function changeDocStatus(status: DocStatusName) {
+    try {
+      const res = await fetch(apiUrl(`/api/file/status?path=${encodeURIComponent(currentFile.value)}`), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ status }),
+      })
+      if (res.ok) {
+        docStatus.value = await res.json()
+      } else {
+        console.error('[Store] 变更文档状态失败:', await res.text())
+      }
+    } catch (err) {
+      console.error('[Store] 变更文档状态失败:', err)
+    }
+  }
+
   /** 从 Markdown 原文中提取 frontmatter scope */
   function extractScope(raw: string): string {
     const trimmed = raw.trimStart()
@@ -146,9 +278,12 @@ export const useWikiStore = defineStore('wiki', () => {
     // 状态
     index,
     fileTree,
+    activeTags,
     currentFile,
     currentContent,
     currentScope,
+    autoLinks,
+    docStatus,
     mode,
     editingContent,
     saveRequestId,
@@ -156,13 +291,26 @@ export const useWikiStore = defineStore('wiki', () => {
     frontendVersion,
     backendVersion,
     versionMismatch,
+    user,
+    readOnly,
+    autoLink,
+    workspaces,
+    currentWorkspace,
     // 计算属性
     currentFileName,
+    tagCounts,
+    filteredFileTree,
+    canReview,
+    canEdit,
     // 操作
     fetchIndex,
     fetchFileTree,
+    toggleTag,
     checkVersion,
+    fetchWorkspaces,
+    switchWorkspace,
     loadFile,
+    changeDocStatus,
     updateIndex,
     requestSave,
   }
@@ -0,0 +1,11 @@
+/**
+ * 文档状态的显示名称
+ */
+import type { DocStatusName } from '@shared/types'
+
+export const statusLabels: Record<DocStatusName, string> = {
+  draft: '草稿',
+  review: '审核中',
+  approved: '已定稿',
+  deprecated: '已废弃',
+}
@@ -12,6 +12,11 @@ export interface ResolveResult {
   exact: boolean
   /** 近似匹配建议（仅当 exact=false 时有值） */
   suggestions: SuggestionItem[]
+  /** 引用的词条已废弃：有 replacedBy 且替代词条存在时，definitions 为替代词条的定义 */
+  deprecated?: {
+    term: string
+    replacedBy?: string
+  }
 }
 
 export interface SuggestionItem {
@@ -20,6 +25,40 @@ export interface SuggestionItem {
   sources: WikiTerm[]
 }
 
+/** 词条引用的组成：【工作区:scope/词条】，工作区和 scope 都可省略 */
+export interface TermRef {
+  /** 其他工作区的名称，引用本工作区时为 null */
+  workspace: string | null
+  /** 显式 scope */
+  scope: string | null
+  /** 词条名 */
+  name: string
+}
+
+/**
+ * 拆分词条引用
+ *
+ * 冒号前是索引中存在的工作区时才视为跨工作区引用，词条名本身可以包含冒号
+ */
+export function parseTermRef(termName: string, index: WikiIndex): TermRef {
+  let workspace: string | null = null
+  let rest = termName
+
+  const colonIndex = termName.indexOf(':')
+  if (colonIndex > 0 && index.external?.[termName.slice(0, colonIndex)]) {
+    workspace = termName.slice(0, colonIndex)
+    rest = termName.slice(colonIndex + 1)
+  }
+
+  // 章节引用 【词条#章节】 的章节标题中可以有 /，只看 # 之前的部分
+  const hashIndex = rest.indexOf('#')
+  const slashIndex = (hashIndex > 0 ? rest.slice(0, hashIndex) : rest).indexOf('/')
+  if (slashIndex > 0) {
+    return { workspace, scope: rest.slice(0, slashIndex), name: rest.slice(slashIndex + 1) }
+  }
+  return { workspace, scope: null, name: rest }
+}
+
 /**
  * 解析词条，按 scope 优先级排序
  *
@@ -29,7 +68,9 @@ export interface SuggestionItem {
  *   3. 全局定义（scope 为空）
  *   4. 其他 scope 的定义（不主动显示，但显式引用 【scope/词条】 时显示）
  *
- * @param termName 词条名（可能包含 scope 前缀，如 "game2/NPC"）
+ * 跨工作区引用 【项目A:词条】 在该工作区的词条中查找，不考虑当前文件和当前 scope
+ *
+ * @param termName 词条名（可能包含工作区和 scope 前缀，如 "game2/NPC"、"项目A:滑移"）
  * @param index 全局索引
  * @param currentScope 当前文件的 scope
  * @param currentFile 当前文件路径
@@ -40,28 +81,40 @@ export function resolveTerm(
   currentScope: string,
   currentFile: string,
 ): ResolveResult {
-  // 处理显式 scope 引用：【scope/词条】
-  let explicitScope: string | null = null
-  let lookupName = termName
-
-  const slashIndex = termName.indexOf('/')
-  if (slashIndex > 0) {
-    explicitScope = termName.slice(0, slashIndex)
-    lookupName = termName.slice(slashIndex + 1)
+  // 处理跨工作区引用 【工作区:词条】 和显式 scope 引用 【scope/词条】
+  const ref = parseTermRef(termName, index)
+  const terms = ref.workspace !== null ? index.external?.[ref.workspace] : index.terms
+  if (ref.workspace !== null) {
+    currentScope = ''
+    currentFile = ''
   }
 
-  const allDefs = index.terms?.[lookupName]
+  const allDefs = terms?.[ref.name]
   if (!allDefs || allDefs.length === 0) {
     // 未找到精确匹配，尝试近似匹配
     return {
       definitions: [],
       exact: false,
-      suggestions: findSuggestions(lookupName, index),
+      suggestions: findSuggestions(ref.name, terms, ref.workspace ? ref.workspace + ':' : ''),
     }
   }
 
   // 按优先级排序
-  const sorted = sortByPriority(allDefs, explicitScope, currentScope, currentFile)
+  const sorted = sortByPriority(allDefs, ref.scope, currentScope, currentFile)
+
+  // 已废弃的词条转到替代词条（只转一次，避免循环）
+  const primary = sorted[0]
+  if (primary.deprecated) {
+    const replacement = primary.replacedBy ? terms?.[primary.replacedBy] : undefined
+    return {
+      definitions: replacement?.length
+        ? sortByPriority(replacement, ref.scope, currentScope, currentFile)
+        : sorted,
+      exact: true,
+      suggestions: [],
+      deprecated: { term: ref.name, replacedBy: primary.replacedBy },
+    }
+  }
 
   return {
     definitions: sorted,
@@ -159,19 +212,22 @@ const MAX_DISTANCE = 3
 
 /**
  * 查找近似匹配的词条
+ *
+ * @param prefix 建议词条的引用前缀（跨工作区引用时为 "工作区:"）
  */
 function findSuggestions(
   query: string,
-  index: WikiIndex,
+  terms: Record<string, WikiTerm[]> | undefined,
+  prefix = '',
 ): SuggestionItem[] {
-  if (!index.terms) return []
+  if (!terms) return []
 
   const candidates: SuggestionItem[] = []
 
-  for (const [alias, terms] of Object.entries(index.terms)) {
+  for (const [alias, sources] of Object.entries(terms)) {
     const dist = levenshteinDistance(query, alias)
     if (dist > 0 && dist <= MAX_DISTANCE) {
-      candidates.push({ term: alias, distance: dist, sources: terms })
+      candidates.push({ term: prefix + alias, distance: dist, sources })
     }
   }
 
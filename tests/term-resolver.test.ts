@@ -3,7 +3,7 @@
  * 测试 scope 优先级、过滤、近似匹配
  */
 import { describe, test, expect } from 'bun:test'
-import { resolveTerm, filterByScope, levenshteinDistance } from '../src/utils/term-resolver'
+import { resolveTerm, filterByScope, levenshteinDistance, parseTermRef } from '../src/utils/term-resolver'
 import type { WikiIndex, WikiTerm } from '../shared/types'
 
 // ─── 测试数据 ─────────────────────────────────────────────
@@ -121,6 +121,89 @@ describe('resolveTerm — 近似匹配', () => {
   })
 })
 
+// ─── 跨工作区引用 ─────────────────────────────────────────
+
+const crossIndex: WikiIndex = {
+  ...testIndex,
+  external: {
+    '通用术语': {
+      '滑移': [makeTerm({ term: '滑移', aliases: ['滑移'], definition: '通用滑移', filePath: '滑移.md', workspace: '通用术语' })],
+      'NPC': [makeTerm({ definition: '战斗 NPC', scope: '战斗', filePath: 'npc.md', workspace: '通用术语' })],
+    },
+  },
+}
+
+describe('resolveTerm — 跨工作区引用', () => {
+  test('【工作区:词条】 在该工作区的词条中查找', () => {
+    const result = resolveTerm('通用术语:滑移', crossIndex, '', 'test.md')
+    expect(result.exact).toBe(true)
+    expect(result.definitions.map(d => d.definition)).toEqual(['通用滑移'])
+  })
+
+  test('可以同时带 scope', () => {
+    expect(parseTermRef('通用术语:战斗/NPC', crossIndex)).toEqual({ workspace: '通用术语', scope: '战斗', name: 'NPC' })
+    const result = resolveTerm('通用术语:战斗/NPC', crossIndex, 'game1', 'test.md')
+    expect(result.definitions[0].definition).toBe('战斗 NPC')
+  })
+
+  test('冒号前不是已知工作区时按普通词条处理', () => {
+    expect(parseTermRef('HP:MP', crossIndex)).toEqual({ workspace: null, scope: null, name: 'HP:MP' })
+  })
+
+  test('拼写错误时建议带工作区前缀', () => {
+    const result = resolveTerm('通用术语:滑动', crossIndex, '', 'test.md')
+    expect(result.exact).toBe(false)
+    expect(result.suggestions.map(s => s.term)).toContain('通用术语:滑移')
+  })
+})
+
+describe('resolveTerm — 章节引用', () => {
+  const sectionIndex: WikiIndex = {
+    ...testIndex,
+    terms: {
+      ...testIndex.terms,
+      '冲击#伤害/治疗': [
+        makeTerm({ term: '冲击#伤害/治疗', aliases: ['冲击#伤害/治疗'], definition: '章节内容', filePath: '冲击.md', definitionType: 'section', line: 12 }),
+      ],
+    },
+  }
+
+  test('章节标题中的 / 不作为 scope 分隔符', () => {
+    expect(parseTermRef('冲击#伤害/治疗', sectionIndex)).toEqual({ workspace: null, scope: null, name: '冲击#伤害/治疗' })
+    expect(parseTermRef('game1/冲击#伤害/治疗', sectionIndex)).toEqual({ workspace: null, scope: 'game1', name: '冲击#伤害/治疗' })
+  })
+
+  test('按 词条#章节 查找', () => {
+    const result = resolveTerm('冲击#伤害/治疗', sectionIndex, '', 'test.md')
+    expect(result.exact).toBe(true)
+    expect(result.definitions[0].line).toBe(12)
+  })
+})
+
+describe('resolveTerm — 废弃词条', () => {
+  const deprecatedIndex: WikiIndex = {
+    ...testIndex,
+    terms: {
+      '删除建筑': [makeTerm({ term: '删除建筑', aliases: ['删除建筑'], definition: '', filePath: '删除建筑.md', deprecated: true, replacedBy: '拆除建筑' })],
+      '拆除建筑': [makeTerm({ term: '拆除建筑', aliases: ['拆除建筑'], definition: '返还资源', filePath: '拆除建筑.md' })],
+      '移除建筑': [makeTerm({ term: '移除建筑', aliases: ['移除建筑'], definition: '旧说法', filePath: '移除建筑.md', deprecated: true })],
+    },
+  }
+
+  test('返回替代词条的定义并附带废弃提示', () => {
+    const result = resolveTerm('删除建筑', deprecatedIndex, '', 'test.md')
+    expect(result.exact).toBe(true)
+    expect(result.definitions.map(d => d.definition)).toEqual(['返还资源'])
+    expect(result.deprecated).toEqual({ term: '删除建筑', replacedBy: '拆除建筑' })
+  })
+
+  test('没有替代词条时保留原定义', () => {
+    const result = resolveTerm('移除建筑', deprecatedIndex, '', 'test.md')
+    expect(result.definitions.map(d => d.definition)).toEqual(['旧说法'])
+    expect(result.deprecated).toEqual({ term: '移除建筑', replacedBy: undefined })
+  })
+})
+
 // ─── levenshteinDistance 测试 ─────────────────────────────
 
 describe('levenshteinDistance', () => {
@@ -8,6 +8,8 @@ export default defineConfig(({ mode }) => {
   const pkg = JSON.parse(readFileSync(resolve(__dirname, 'package.json'), 'utf8'))
 
   return {
+    // 资源使用相对路径，由服务端注入的 <base href> 决定实际前缀（支持反向代理子路径部署）
+    base: './',
     plugins: [
       vue(),
     ],
@@ -44,6 +46,11 @@ export default defineConfig(({ mode }) => {
       minify: isDebug ? false : 'esbuild',
       sourcemap: isDebug ? 'inline' : false,
       cssMinify: isDebug ? false : true,
+      rollupOptions: {
+        // 打包为单个脚本：单文件导出（export -single）需要把脚本内联进页面，
+        // 拆分的 chunk 在 file:// 下无法加载
+        output: { inlineDynamicImports: true },
+      },
     },
   }
 })